@@ -0,0 +1,31 @@
+package prosefmt
+
+import "fmt"
+
+const (
+	exitOK     = 0
+	exitIssues = 1
+	exitError  = 2
+)
+
+var (
+	exitCode       = exitOK
+	nothingToCheck bool
+)
+
+func resultExitCode(hadIssues bool) int {
+	switch {
+	case nothingToCheck:
+		return opts.noFilesExitCode
+	case hadIssues:
+		return exitIssues
+	}
+	return exitOK
+}
+
+func parseExitCode(n int) (int, error) {
+	if n < 0 || n > 125 {
+		return 0, fmt.Errorf("%d is not an exit code (want 0-125)", n)
+	}
+	return n, nil
+}
@@ -1,16 +1,30 @@
 package prosefmt
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"prosefmt/internal/backup"
+	"prosefmt/internal/charset"
+	"prosefmt/internal/config"
+	"prosefmt/internal/custom"
 	"prosefmt/internal/fix"
+	"prosefmt/internal/interactive"
 	"prosefmt/internal/log"
+	"prosefmt/internal/lsp"
+	"prosefmt/internal/plugin"
 	"prosefmt/internal/report"
 	"prosefmt/internal/rules"
 	"prosefmt/internal/scanner"
+	"prosefmt/internal/watch"
 	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -18,10 +32,28 @@ import (
 )
 
 var (
-	version        = "dev"
-	checkHadIssues bool
+	version = "dev"
+	cfg     *config.Config
+	opts    runOptions
 )
 
+type runOptions struct {
+	extra           []rules.Rule
+	convertEncoding bool
+	maxFileSize     int64
+	selection       rules.Selection
+	failOn          rules.Severity
+	maxIssues       int
+	noFilesExitCode int
+	keepGoing       bool
+	atomicBatch     bool
+	backup          bool
+	watch           bool
+	interactive     bool
+	format          report.Format
+	color           bool
+}
+
 const rootDescription = "The simplest text formatter for making your files look correct."
 
 var rootCmd = &cobra.Command{
@@ -43,7 +75,7 @@ var versionCmd = &cobra.Command{
 var checkCmd = &cobra.Command{
 	Use:   "check [flags] paths...",
 	Short: "Review the given paths for format issues (default)",
-	Long:  "Recursively scan the given paths and check any non-binary files for format issues. Exit with code 1 if any problems are detected; otherwise, exit with code 0. This is the default behavior when no command is specified.",
+	Long:  "Recursively scan the given paths and check any non-binary files for format issues. Exit with code 1 if any problem at or above the --fail-on severity is detected (or more than --max-issues of them), with code 2 on errors; otherwise, exit with code 0. This is the default behavior when no command is specified.",
 	Args:  cobra.ArbitraryArgs,
 	RunE:  checkRunE,
 }
@@ -56,20 +88,175 @@ var writeCmd = &cobra.Command{
 	RunE:  writeRunE,
 }
 
+var undoCmd = &cobra.Command{
+	Use:   "undo [flags]",
+	Short: "Restore the files changed by the last 'write --backup'",
+	Long:  "Restore the originals saved by the last 'write --backup' run and delete that backup. Files modified since that run are not overwritten unless --force is given.",
+	Args:  cobra.NoArgs,
+	RunE:  undoRunE,
+}
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp [flags]",
+	Short: "Run a language server over stdio",
+	Long:  "Speak the Language Server Protocol over stdin and stdout: publish diagnostics for open documents, offer quick fixes and format documents, using the nearest " + config.FileName + " of each document.",
+	Args:  cobra.NoArgs,
+	RunE:  lspRunE,
+}
+
 func addOutputFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("silent", false, "No output printed")
-	cmd.Flags().Bool("compact", false, "Show formatted or errored files (default)")
+	cmd.Flags().Bool("compact", false, "One line per issue (default when stdout is not a terminal)")
+	cmd.Flags().Bool("pretty", false, "Group issues by file with the source line and a per-rule summary (default on a terminal)")
 	cmd.Flags().Bool("verbose", false, "Print debug output (steps, scanner, rules, timing)")
 }
 
+func addConfigFlag(cmd *cobra.Command) {
+	cmd.Flags().String("config", "", "Path to the config file (default: nearest "+config.FileName+")")
+}
+
+func loadConfig(cmd *cobra.Command) error {
+	file, _ := cmd.Flags().GetString("config")
+	if file == "" {
+		found, err := config.Find(".")
+		if err != nil {
+			return err
+		}
+		if found == "" {
+			cfg = nil
+			return nil
+		}
+		file = found
+	}
+	c, err := config.Load(file)
+	if err != nil {
+		return err
+	}
+	cfg = c
+	log.Logf(log.Verbose, "Config: %s\n", file)
+	return nil
+}
+
+func setup(cmd *cobra.Command) error {
+	cmd.SilenceUsage = true
+	if err := loadConfig(cmd); err != nil {
+		return err
+	}
+	o, err := optionsFromCmd(cmd)
+	if err != nil {
+		return err
+	}
+	opts = o
+	return nil
+}
+
+func optionsFromCmd(cmd *cobra.Command) (runOptions, error) {
+	o := runOptions{maxIssues: -1}
+	plugins := plugin.Rules(cfg)
+	customRules, err := custom.Rules(cfg)
+	if err != nil {
+		return o, err
+	}
+	if err := rules.ValidateExtra(append(plugins, customRules...)); err != nil {
+		return o, err
+	}
+	o.extra = customRules
+	if allow, _ := cmd.Flags().GetBool("allow-plugins"); allow {
+		o.extra = append(plugins, customRules...)
+	} else if len(plugins) > 0 {
+		log.Logf(log.Normal, "Not running %d plugin(s) from the config; pass --allow-plugins to run them.\n", len(plugins))
+	}
+	o.convertEncoding, _ = cmd.Flags().GetBool("convert-encoding")
+	o.atomicBatch, _ = cmd.Flags().GetBool("atomic-batch")
+	o.backup, _ = cmd.Flags().GetBool("backup")
+	o.watch, _ = cmd.Flags().GetBool("watch")
+	o.interactive, _ = cmd.Flags().GetBool("interactive")
+	if o.interactive && (o.atomicBatch || o.watch) {
+		return o, fmt.Errorf("--interactive cannot be combined with --atomic-batch or --watch")
+	}
+	compact, _ := cmd.Flags().GetBool("compact")
+	pretty, _ := cmd.Flags().GetBool("pretty")
+	if compact && pretty {
+		return o, fmt.Errorf("--compact cannot be combined with --pretty")
+	}
+	o.format = report.FormatCompact
+	if pretty || !compact && isTerminal(os.Stdout) {
+		o.format = report.FormatPretty
+		o.color = useColor(os.Stdout)
+	}
+	if v, _ := cmd.Flags().GetString("max-file-size"); v != "" {
+		n, err := parseSize(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid --max-file-size: %w", err)
+		}
+		o.maxFileSize = n
+	}
+	only, _ := cmd.Flags().GetStringSlice("only")
+	skip, _ := cmd.Flags().GetStringSlice("skip")
+	if cfg != nil {
+		if len(only) == 0 {
+			only = cfg.Only
+		}
+		skip = append(skip, cfg.Skip...)
+	}
+	sel, err := rules.NewSelection(only, skip, append(plugins, customRules...)...)
+	if err != nil {
+		return o, err
+	}
+	o.selection = sel
+	if v, _ := cmd.Flags().GetString("fail-on"); v != "" {
+		sev, err := rules.ParseSeverity(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid --fail-on: %w", err)
+		}
+		o.failOn = sev
+	}
+	if cmd.Flags().Lookup("max-issues") != nil {
+		o.maxIssues, _ = cmd.Flags().GetInt("max-issues")
+	}
+	code, _ := cmd.Flags().GetInt("no-files-exit-code")
+	code, err = parseExitCode(code)
+	if err != nil {
+		return o, fmt.Errorf("invalid --no-files-exit-code: %w", err)
+	}
+	o.noFilesExitCode = code
+	o.keepGoing = true
+	if cmd.Flags().Lookup("keep-going") != nil {
+		o.keepGoing, _ = cmd.Flags().GetBool("keep-going")
+	}
+	return o, nil
+}
+
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}}
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size (e.g. 512K, 10M)", s)
+	}
+	return n * mult, nil
+}
+
 func outputLevelFromCmd(cmd *cobra.Command) log.Level {
 	silent, _ := cmd.Flags().GetBool("silent")
 	compact, _ := cmd.Flags().GetBool("compact")
+	pretty, _ := cmd.Flags().GetBool("pretty")
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	if verbose {
 		return log.Verbose
 	}
-	if compact {
+	if compact || pretty {
 		return log.Normal
 	}
 	if silent {
@@ -82,14 +269,42 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(checkCmd)
 	rootCmd.AddCommand(writeCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(lspCmd)
 	addOutputFlags(checkCmd)
 	addOutputFlags(writeCmd)
+	addOutputFlags(undoCmd)
+	addConfigFlag(checkCmd)
+	addConfigFlag(writeCmd)
+	addConfigFlag(undoCmd)
+	addConfigFlag(lspCmd)
+	lspCmd.Flags().Bool("verbose", false, "Log requests that fail on stderr")
+	checkCmd.Flags().Int("max-issues", -1, "Exit with code 0 as long as there are at most this many failing issues (-1: no budget)")
+	checkCmd.Flags().String("fail-on", "error", "Exit with code 1 only for issues of at least this severity (info, warning, error)")
+	writeCmd.Flags().Bool("convert-encoding", false, "Transcode UTF-16, Windows-1252 and Latin-1 files to UTF-8")
+	writeCmd.Flags().Bool("atomic-batch", false, "Stage and verify all fixes first, then write every file or none")
+	writeCmd.Flags().Bool("backup", false, "Keep the original of every written file for 'prosefmt undo'")
+	writeCmd.Flags().Bool("interactive", false, "Review each fix and choose which ones to apply")
+	undoCmd.Flags().Bool("force", false, "Restore files even if they were modified since the last write")
+	for _, c := range []*cobra.Command{checkCmd, writeCmd, lspCmd} {
+		c.Flags().Bool("allow-plugins", false, "Run the plugin commands listed in the config")
+	}
+	for _, c := range []*cobra.Command{checkCmd, writeCmd} {
+		c.Flags().String("max-file-size", "", "Skip files larger than this size (e.g. 512K, 10M; default: no limit)")
+		c.Flags().StringSlice("only", nil, "Run only these rules (comma-separated, repeatable)")
+		c.Flags().StringSlice("skip", nil, "Do not run these rules (comma-separated, repeatable)")
+		c.Flags().Int("no-files-exit-code", exitOK, "Exit code when no text files are found")
+		c.Flags().Bool("keep-going", c == checkCmd, "Record per-file errors and continue; exit with code 2 at the end")
+		c.Flags().Bool("watch", false, "Keep running and re-run on the files that change")
+	}
+	rootCmd.SilenceErrors = true
 	rootCmd.SetHelpFunc(rootHelpFunc)
 	checkCmd.SetHelpFunc(commandHelpFunc)
 	writeCmd.SetHelpFunc(commandHelpFunc)
+	undoCmd.SetHelpFunc(commandHelpFunc)
 }
 
-var outputFlagOrder = []string{"silent", "compact", "verbose"}
+var outputFlagOrder = []string{"silent", "compact", "pretty", "verbose"}
 
 func rootHelpFunc(cmd *cobra.Command, args []string) {
 	out := cmd.OutOrStderr()
@@ -112,7 +327,7 @@ func rootHelpFunc(cmd *cobra.Command, args []string) {
 		}
 		fmt.Fprintln(out, "")
 	}
-	fmt.Fprintln(out, "With no command, runs 'check' by default. Use 'check' or 'write' for output options (--silent, --compact, --verbose).")
+	fmt.Fprintln(out, "With no command, runs 'check' by default. Use 'check' or 'write' for output options (--silent, --compact, --pretty, --verbose).")
 	if version != "" {
 		fmt.Fprintf(out, "\nVersion: %s\n", version)
 	}
@@ -159,11 +374,32 @@ func commandHelpFunc(cmd *cobra.Command, args []string) {
 			printFlagUsage(out, f)
 		}
 	}
+	var options []*pflag.Flag
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name != "help" && !isOutputFlag(f.Name) {
+			options = append(options, f)
+		}
+	})
+	if len(options) > 0 {
+		fmt.Fprintln(out, "\nOptions:")
+		for _, f := range options {
+			printFlagUsage(out, f)
+		}
+	}
 	if version != "" {
 		fmt.Fprintf(out, "\nVersion: %s\n", version)
 	}
 }
 
+func isOutputFlag(name string) bool {
+	for _, n := range outputFlagOrder {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
 func printFlagUsage(out io.Writer, f *pflag.Flag) {
 	if f.Shorthand != "" && f.Name != f.Shorthand {
 		fmt.Fprintf(out, "  -%s, --%s\t%s\n", f.Shorthand, f.Name, f.Usage)
@@ -175,11 +411,9 @@ func printFlagUsage(out io.Writer, f *pflag.Flag) {
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
-	if checkHadIssues {
-		os.Exit(1)
+		os.Exit(exitError)
 	}
+	os.Exit(exitCode)
 }
 
 func rootRunE(cmd *cobra.Command, args []string) error {
@@ -188,11 +422,14 @@ func rootRunE(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	log.SetLevel(log.Normal)
+	if err := setup(cmd); err != nil {
+		return err
+	}
 	hadIssues, err := run(true, false, args)
 	if err != nil {
 		return err
 	}
-	checkHadIssues = hadIssues
+	exitCode = resultExitCode(hadIssues)
 	return nil
 }
 
@@ -202,11 +439,17 @@ func checkRunE(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	log.SetLevel(outputLevelFromCmd(cmd))
+	if err := setup(cmd); err != nil {
+		return err
+	}
+	if opts.watch {
+		return watchPaths(true, false, args)
+	}
 	hadIssues, err := run(true, false, args)
 	if err != nil {
 		return err
 	}
-	checkHadIssues = hadIssues
+	exitCode = resultExitCode(hadIssues)
 	return nil
 }
 
@@ -216,20 +459,59 @@ func writeRunE(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	log.SetLevel(outputLevelFromCmd(cmd))
+	if err := setup(cmd); err != nil {
+		return err
+	}
+	if opts.watch {
+		return watchPaths(false, true, args)
+	}
 	_, err := run(false, true, args)
-	return err
+	if err != nil {
+		return err
+	}
+	exitCode = resultExitCode(false)
+	return nil
 }
 
 func run(check, doWrite bool, paths []string) (hadIssues bool, err error) {
 	start := time.Now()
+	nothingToCheck = false
 	lvl := log.GetLevel()
 	if lvl >= log.Verbose {
 		log.Logf(log.Verbose, "Configuration: check=%v paths=%v\n", check, paths)
 	}
-	files, skipped, err := scanner.Scan(paths)
+	var fileErrors []report.FileError
+	fail := func(path string, err error) error {
+		if !opts.keepGoing {
+			return err
+		}
+		fileErrors = append(fileErrors, report.FileError{File: path, Err: err})
+		if lvl >= log.Verbose {
+			log.Logf(log.Verbose, "error: %s (%v)\n", path, err)
+		}
+		return nil
+	}
+	scanOpts := scanner.Options{MaxFileSize: opts.maxFileSize}
+	if opts.keepGoing {
+		scanOpts.OnError = func(path string, err error) { fail(path, err) }
+	}
+	scanned, skipped, err := scanner.ScanFiles(paths, scanOpts)
 	if err != nil {
 		return false, err
 	}
+	files := make([]string, 0, len(scanned))
+	types := make(map[string]string, len(scanned))
+	roots := make(map[string]string, len(scanned))
+	for _, f := range scanned {
+		files = append(files, f.Path)
+		types[f.Path] = f.Type
+		roots[f.Path] = f.Root
+	}
+	optionsFor := func(path string) rules.Options {
+		o := ruleOptions(path, types[path])
+		o.Root = roots[path]
+		return o
+	}
 	elapsedScan := time.Since(start)
 	if lvl >= log.Verbose {
 		if len(files) == 0 {
@@ -240,21 +522,32 @@ func run(check, doWrite bool, paths []string) (hadIssues bool, err error) {
 		for _, p := range sortedKeys(skipped) {
 			log.Logf(log.Verbose, "scanner: rejected %s (reason: %s)\n", p, skipped[p])
 		}
-		for _, p := range files {
-			log.Logf(log.Verbose, "scanner: accepted %s\n", p)
+		for _, f := range scanned {
+			if f.Encoding != charset.UTF8 {
+				log.Logf(log.Verbose, "scanner: accepted %s (type: %s, encoding: %s)\n", f.Path, f.Type, f.Encoding)
+				continue
+			}
+			log.Logf(log.Verbose, "scanner: accepted %s (type: %s)\n", f.Path, f.Type)
 		}
 	}
 	if len(files) == 0 {
 		if lvl >= log.Normal {
 			fmt.Fprintln(os.Stdout, "No text files found.")
 			if check {
-				report.Write(os.Stdout, report.FormatCompact, nil, 0, nil)
+				writeReport(nil, 0, nil)
 			}
 		}
-		return false, nil
+		nothingToCheck = true
+		return false, reportFileErrors(fileErrors, lvl)
 	}
 	var allIssues []rules.Issue
+	sources := make(map[string][]byte)
 	fileIssues := make(map[string][]rules.Issue)
+	pathIssues := make(map[string][]rules.Issue)
+	for _, i := range rules.CheckPaths(files, optionsFor) {
+		pathIssues[i.File] = append(pathIssues[i.File], i)
+	}
+	checked := 0
 	for _, path := range files {
 		if lvl >= log.Verbose {
 			if check {
@@ -263,12 +556,30 @@ func run(check, doWrite bool, paths []string) (hadIssues bool, err error) {
 				log.Logf(log.Verbose, "Writing %s\n", path)
 			}
 		}
-		issues, err := rules.CheckFile(path)
+		content, err := os.ReadFile(path)
 		if err != nil {
-			return false, err
+			if err := fail(path, err); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if _, err := scanner.Validate(content); err != nil {
+			if lvl >= log.Verbose {
+				log.Logf(log.Verbose, "scanner: rejected %s after reading full content (%v)\n", path, err)
+			}
+			continue
+		}
+		checked++
+		issues := rules.CheckWith(path, content, optionsFor(path))
+		if lvl >= log.Verbose {
+			for _, i := range rules.Suppressed(path, content, optionsFor(path)) {
+				log.Logf(log.Verbose, "rules: %s skipped at %s:%d:%d (%s)\n", i.RuleID, path, i.Line, i.Column, rules.ProtectionReason(path, optionsFor(path)))
+			}
 		}
+		issues = append(pathIssues[path], issues...)
 		if len(issues) > 0 {
 			fileIssues[path] = issues
+			sources[path] = content
 			allIssues = append(allIssues, issues...)
 			if lvl >= log.Verbose {
 				ruleIDs := make(map[string]bool)
@@ -286,33 +597,139 @@ func run(check, doWrite bool, paths []string) (hadIssues bool, err error) {
 	}
 	if check {
 		if lvl >= log.Normal {
-			if err := report.Write(os.Stdout, report.FormatCompact, allIssues, len(files), files); err != nil {
+			if err := writeReport(allIssues, checked, sources); err != nil {
 				return false, err
 			}
 		}
+		errsErr := reportFileErrors(fileErrors, lvl)
 		elapsed := time.Since(start)
 		if lvl >= log.Verbose {
 			log.Logf(log.Verbose, "Completed in %s\n", elapsed.Round(time.Millisecond))
 		}
 		_ = elapsedScan
-		return len(allIssues) > 0, nil
+		n := failing(allIssues, opts.failOn)
+		if opts.maxIssues >= 0 {
+			if lvl >= log.Verbose {
+				log.Logf(log.Verbose, "Issue budget: %d of %d failing issue(s)\n", n, opts.maxIssues)
+			}
+			return n > opts.maxIssues, errsErr
+		}
+		return n > 0, errsErr
+	}
+	var written, conflicted []string
+	var batch *fix.Batch
+	apply := fix.ApplyWith
+	if opts.atomicBatch {
+		batch = &fix.Batch{}
+		apply = batch.Stage
 	}
+	var backups *backup.Set
+	var session *interactive.Session
+	if opts.interactive {
+		session = interactive.New(os.Stdin, os.Stdout, useColor(os.Stdout))
+		apply = func(path string, o rules.Options) (bool, error) {
+			var reviewed []byte
+			changed, err := fix.Rewrite(path, func(content []byte) ([]byte, error) {
+				out, err := session.Review(path, content, o)
+				reviewed = out
+				return out, err
+			})
+			if changed && backups != nil {
+				backups.Update(path, reviewed)
+			}
+			return changed, err
+		}
+	}
+	if opts.backup {
+		backups = backup.New(projectRoot(), time.Now())
+		apply = withBackup(apply, backups)
+	}
+	fixable := make([]string, 0, len(fileIssues))
 	for path := range fileIssues {
-		if err := fix.Apply(path); err != nil {
+		fixable = append(fixable, path)
+	}
+	sort.Strings(fixable)
+	for _, path := range fixable {
+		if session != nil && session.Quit() {
+			break
+		}
+		changed, err := apply(path, optionsFor(path))
+		if scanner.IsNotText(err) {
+			if lvl >= log.Verbose {
+				log.Logf(log.Verbose, "write: refused %s (%v)\n", path, err)
+			}
+			continue
+		}
+		if err != nil {
+			if batch != nil {
+				batch.Abort()
+				if backups != nil {
+					backups.Remove()
+				}
+				return false, err
+			}
+			if err := fail(path, err); err != nil {
+				if backups != nil {
+					backups.Close()
+				}
+				return false, err
+			}
+			continue
+		}
+		if !changed {
+			reason := "no fixable issues"
+			if hasRuleIssue(fileIssues[path], rules.TL002ID) && !opts.convertEncoding {
+				reason = "not UTF-8, use --convert-encoding"
+			} else if hasRuleIssue(fileIssues[path], rules.TL030ID) {
+				reason = "merge conflict markers"
+				conflicted = append(conflicted, path)
+			} else if session != nil {
+				reason = "no fix accepted"
+			}
+			if lvl >= log.Verbose {
+				log.Logf(log.Verbose, "write: skipped %s (%s)\n", path, reason)
+			}
+			continue
+		}
+		written = append(written, path)
+		if lvl >= log.Verbose {
+			if batch != nil {
+				log.Logf(log.Verbose, "write: staged %s\n", path)
+			} else {
+				log.Logf(log.Verbose, "write: applied to %s\n", path)
+			}
+		}
+	}
+	if batch != nil {
+		if err := batch.Commit(); err != nil {
+			if backups != nil {
+				backups.Remove()
+			}
 			return false, err
 		}
 		if lvl >= log.Verbose {
-			log.Logf(log.Verbose, "write: applied to %s\n", path)
+			log.Logf(log.Verbose, "write: committed %d staged file(s)\n", len(written))
 		}
 	}
-	if lvl >= log.Normal && len(fileIssues) > 0 {
-		paths := make([]string, 0, len(fileIssues))
-		for p := range fileIssues {
-			paths = append(paths, p)
+	if backups != nil {
+		if err := backups.Close(); err != nil {
+			return false, err
 		}
-		sort.Strings(paths)
-		fmt.Fprintf(os.Stdout, "Wrote %d file(s):\n", len(paths))
-		for _, p := range paths {
+		if lvl >= log.Verbose && len(written) > 0 {
+			log.Logf(log.Verbose, "write: originals saved to %s\n", backups.Dir())
+		}
+	}
+	if lvl >= log.Normal && len(written) > 0 {
+		sort.Strings(written)
+		fmt.Fprintf(os.Stdout, "Wrote %d file(s):\n", len(written))
+		for _, p := range written {
+			fmt.Fprintln(os.Stdout, p)
+		}
+	}
+	if lvl >= log.Normal && len(conflicted) > 0 {
+		sort.Strings(conflicted)
+		fmt.Fprintf(os.Stdout, "Skipped %d file(s) with merge conflict markers:\n", len(conflicted))
+		for _, p := range conflicted {
 			fmt.Fprintln(os.Stdout, p)
 		}
 	}
@@ -320,7 +737,153 @@ func run(check, doWrite bool, paths []string) (hadIssues bool, err error) {
 	if lvl >= log.Verbose {
 		log.Logf(log.Verbose, "Completed in %s\n", elapsed.Round(time.Millisecond))
 	}
-	return false, nil
+	return false, reportFileErrors(fileErrors, lvl)
+}
+
+func writeReport(issues []rules.Issue, checked int, sources map[string][]byte) error {
+	return report.Write(os.Stdout, opts.format, issues, checked, sources, opts.color)
+}
+
+func reportFileErrors(errs []report.FileError, lvl log.Level) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	if lvl >= log.Normal {
+		if err := report.WriteErrors(os.Stdout, errs); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%d file(s) could not be processed", len(errs))
+}
+
+func failing(issues []rules.Issue, threshold rules.Severity) int {
+	n := 0
+	for _, i := range issues {
+		if i.Severity.AtLeast(threshold) {
+			n++
+		}
+	}
+	return n
+}
+
+func watchPaths(check, doWrite bool, paths []string) error {
+	if _, err := run(check, doWrite, paths); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Logf(log.Normal, "Watching %s for changes (Ctrl-C to stop)\n", strings.Join(paths, ", "))
+	return watch.Watch(ctx, paths, watch.Options{}, func(changed []string) {
+		scanned, _, _ := scanner.ScanFiles(changed, scanner.Options{
+			MaxFileSize: opts.maxFileSize,
+			OnError:     func(string, error) {},
+		})
+		if len(scanned) == 0 {
+			return
+		}
+		affected := make([]string, 0, len(scanned))
+		for _, f := range scanned {
+			affected = append(affected, f.Path)
+		}
+		if log.GetLevel() >= log.Normal {
+			fmt.Fprintf(os.Stdout, "\n[%s] %d file(s) changed\n", time.Now().Format("15:04:05"), len(affected))
+		}
+		if _, err := run(check, doWrite, affected); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	})
+}
+
+func withBackup(apply func(string, rules.Options) (bool, error), backups *backup.Set) func(string, rules.Options) (bool, error) {
+	return func(path string, o rules.Options) (bool, error) {
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		if fixed := rules.FixWith(original, o); !bytes.Equal(fixed, original) {
+			if err := backups.Save(path, original, fixed); err != nil {
+				return false, err
+			}
+		}
+		changed, err := apply(path, o)
+		if err != nil || !changed {
+			backups.Discard(path)
+		}
+		return changed, err
+	}
+}
+
+func useColor(f *os.File) bool {
+	return os.Getenv("NO_COLOR") == "" && isTerminal(f)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func projectRoot() string {
+	if cfg != nil {
+		return cfg.Dir
+	}
+	return "."
+}
+
+func undoRunE(cmd *cobra.Command, args []string) error {
+	log.SetLevel(outputLevelFromCmd(cmd))
+	cmd.SilenceUsage = true
+	if err := loadConfig(cmd); err != nil {
+		return err
+	}
+	set, err := backup.Latest(projectRoot())
+	if err != nil {
+		return err
+	}
+	force, _ := cmd.Flags().GetBool("force")
+	restored, err := set.Restore(force)
+	var modified *backup.ModifiedError
+	if errors.As(err, &modified) {
+		return fmt.Errorf("%w (use --force to restore anyway)", err)
+	}
+	if err != nil {
+		return err
+	}
+	if log.GetLevel() >= log.Normal {
+		fmt.Fprintf(os.Stdout, "Restored %d file(s):\n", len(restored))
+		for _, p := range restored {
+			fmt.Fprintln(os.Stdout, p)
+		}
+	}
+	return nil
+}
+
+func lspRunE(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	log.SetLevel(outputLevelFromCmd(cmd))
+	file, _ := cmd.Flags().GetString("config")
+	s := lsp.New(file, version)
+	s.AllowPlugins, _ = cmd.Flags().GetBool("allow-plugins")
+	return s.Run(os.Stdin, os.Stdout)
+}
+
+func hasRuleIssue(issues []rules.Issue, id string) bool {
+	for _, i := range issues {
+		if i.RuleID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleOptions(path, fileType string) rules.Options {
+	return rules.Options{
+		File:            path,
+		Type:            fileType,
+		Rules:           cfg.For(path, fileType),
+		Select:          opts.selection,
+		ConvertEncoding: opts.convertEncoding,
+		Extra:           opts.extra,
+	}
 }
 
 func sortedKeys(m map[string]string) []string {
@@ -179,3 +179,68 @@ func TestRun_ZeroTextFiles_Normal_NoTextFilesFound(t *testing.T) {
 		t.Errorf("expected 0 file(s) scanned, 0 issue(s) in summary, got %q", stdout)
 	}
 }
+
+func TestRun_Write_BinaryTailNotWritten(t *testing.T) {
+	dir := t.TempDir()
+	f := filepath.Join(dir, "mixed.txt")
+	content := append(bytes.Repeat([]byte("text  \n"), 10000), 0x00, 0xFF)
+	if err := os.WriteFile(f, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	log.SetLevel(log.Verbose)
+	defer log.SetLevel(log.Normal)
+	var runErr error
+	stderr := captureStderr(func() {
+		_, runErr = run(false, true, []string{f})
+	})
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+	after, err := os.ReadFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, content) {
+		t.Error("expected file with binary tail to be left untouched")
+	}
+	if !strings.Contains(stderr, "null byte") {
+		t.Errorf("expected rejection reason on stderr, got %q", stderr)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{"100": 100, "512K": 512 << 10, "10mb": 10 << 20, "1G": 1 << 30}
+	for in, want := range tests {
+		got, err := parseSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := parseSize("ten"); err == nil {
+		t.Error("expected error for invalid size")
+	}
+}
+
+func TestRun_Verbose_ReportsProtectedSkip(t *testing.T) {
+	dir := t.TempDir()
+	f := filepath.Join(dir, "Makefile")
+	if err := os.WriteFile(f, []byte("all:\n\t\n\ttrue\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	log.SetLevel(log.Verbose)
+	defer log.SetLevel(log.Normal)
+	var hadIssues bool
+	var runErr error
+	stderr := captureStderr(func() {
+		hadIssues, runErr = run(true, false, []string{f})
+	})
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+	if hadIssues {
+		t.Error("expected no issues for a recipe tab line")
+	}
+	if !strings.Contains(stderr, "rules: TL010 skipped at") || !strings.Contains(stderr, "recipe tabs") {
+		t.Errorf("expected protected skip on stderr, got %q", stderr)
+	}
+}
@@ -0,0 +1,241 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DirName      = ".prosefmt"
+	ManifestName = "manifest.json"
+)
+
+type Manifest struct {
+	Created time.Time `json:"created"`
+	Files   []Entry   `json:"files"`
+}
+
+type Entry struct {
+	Path   string      `json:"path"`
+	Backup string      `json:"backup"`
+	SHA256 string      `json:"sha256"`
+	Mode   os.FileMode `json:"mode,omitempty"`
+}
+
+const defaultMode os.FileMode = 0644
+
+type Set struct {
+	root     string
+	dir      string
+	manifest Manifest
+}
+
+type ModifiedError struct {
+	Paths []string
+}
+
+func (e *ModifiedError) Error() string {
+	return fmt.Sprintf("%d file(s) modified since the last write: %s", len(e.Paths), strings.Join(e.Paths, ", "))
+}
+
+func BackupsDir(root string) string {
+	return filepath.Join(root, DirName, "backups")
+}
+
+func New(root string, now time.Time) *Set {
+	root, _ = filepath.Abs(root)
+	return &Set{
+		root:     root,
+		dir:      filepath.Join(BackupsDir(root), now.UTC().Format("20060102T150405.000000000Z")),
+		manifest: Manifest{Created: now.UTC()},
+	}
+}
+
+func (s *Set) Dir() string {
+	return s.dir
+}
+
+func (s *Set) Save(path string, original, fixed []byte) error {
+	rel := s.rel(path)
+	stored := rel
+	if filepath.IsAbs(rel) {
+		stored = filepath.Join("_abs", strings.TrimLeft(rel[len(filepath.VolumeName(rel)):], `\/`))
+	}
+	backup := filepath.ToSlash(filepath.Join("files", stored))
+	dst := filepath.Join(s.dir, filepath.FromSlash(backup))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	mode := defaultMode
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := writeFile(dst, original, mode); err != nil {
+		return err
+	}
+	s.manifest.Files = append(s.manifest.Files, Entry{Path: filepath.ToSlash(rel), Backup: backup, SHA256: hash(fixed), Mode: mode})
+	return nil
+}
+
+func (s *Set) Update(path string, written []byte) {
+	rel := filepath.ToSlash(s.rel(path))
+	for i, e := range s.manifest.Files {
+		if e.Path == rel {
+			s.manifest.Files[i].SHA256 = hash(written)
+			return
+		}
+	}
+}
+
+func (s *Set) Discard(path string) {
+	rel := filepath.ToSlash(s.rel(path))
+	for i, e := range s.manifest.Files {
+		if e.Path == rel {
+			os.Remove(filepath.Join(s.dir, filepath.FromSlash(e.Backup)))
+			s.manifest.Files = append(s.manifest.Files[:i], s.manifest.Files[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Set) Close() error {
+	if len(s.manifest.Files) == 0 {
+		return os.RemoveAll(s.dir)
+	}
+	sort.Slice(s.manifest.Files, func(a, b int) bool { return s.manifest.Files[a].Path < s.manifest.Files[b].Path })
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, ManifestName), append(data, '\n'), 0644)
+}
+
+func (s *Set) Remove() error {
+	return os.RemoveAll(s.dir)
+}
+
+func (s *Set) rel(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(s.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return abs
+	}
+	return rel
+}
+
+func Latest(root string) (*Set, error) {
+	root, _ = filepath.Abs(root)
+	entries, err := os.ReadDir(BackupsDir(root))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("no backups to undo")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		dir := filepath.Join(BackupsDir(root), entries[i].Name())
+		data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s := &Set{root: root, dir: dir}
+		if err := json.Unmarshal(data, &s.manifest); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(dir, ManifestName), err)
+		}
+		return s, nil
+	}
+	return nil, errors.New("no backups to undo")
+}
+
+func (s *Set) Files() []string {
+	var paths []string
+	for _, e := range s.manifest.Files {
+		paths = append(paths, s.path(e))
+	}
+	return paths
+}
+
+func (s *Set) Modified() ([]string, error) {
+	var modified []string
+	for _, e := range s.manifest.Files {
+		current, err := os.ReadFile(s.path(e))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err != nil || hash(current) != e.SHA256 {
+			modified = append(modified, s.path(e))
+		}
+	}
+	return modified, nil
+}
+
+func (s *Set) Restore(force bool) ([]string, error) {
+	if !force {
+		modified, err := s.Modified()
+		if err != nil {
+			return nil, err
+		}
+		if len(modified) > 0 {
+			return nil, &ModifiedError{Paths: modified}
+		}
+	}
+	var restored []string
+	for _, e := range s.manifest.Files {
+		original, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(e.Backup)))
+		if err != nil {
+			return restored, err
+		}
+		path := s.path(e)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return restored, err
+		}
+		mode := e.Mode
+		if mode == 0 {
+			mode = defaultMode
+		}
+		if err := writeFile(path, original, mode); err != nil {
+			return restored, err
+		}
+		restored = append(restored, path)
+	}
+	return restored, s.Remove()
+}
+
+func (s *Set) path(e Entry) string {
+	p := filepath.FromSlash(e.Path)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(s.root, p)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, p); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return p
+}
+
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
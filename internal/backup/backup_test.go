@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestore(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "docs", "a.txt")
+	if err := os.MkdirAll(filepath.Dir(a), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(a, []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(root, time.Unix(0, 0))
+	if err := s.Save(a, []byte("a  \n"), []byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(filepath.Join(root, "b.txt"), []byte("b"), []byte("b\n")); err != nil {
+		t.Fatal(err)
+	}
+	s.Discard(filepath.Join(root, "b.txt"))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := Latest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := latest.Files(); len(files) != 1 {
+		t.Fatalf("expected one file in manifest, got %v", files)
+	}
+	if _, err := latest.Restore(false); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "a  \n" {
+		t.Errorf("expected original restored, got %q", got)
+	}
+	if _, err := Latest(root); err == nil {
+		t.Error("expected restored backup to be removed")
+	}
+}
+
+func TestRestore_KeepsFileMode(t *testing.T) {
+	root := t.TempDir()
+	script := filepath.Join(root, "run.sh")
+	if err := os.WriteFile(script, []byte("echo hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := New(root, time.Unix(0, 0))
+	if err := s.Save(script, []byte("echo hi  \n"), []byte("echo hi\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(script); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, []byte("echo hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := Latest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := latest.Restore(false); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(script); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755 restored, got %v (%v)", fi.Mode(), err)
+	}
+}
+
+func TestRestore_RefusesModified(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(a, []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(root, time.Now())
+	if err := s.Save(a, []byte("a  \n"), []byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := Latest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modified *ModifiedError
+	if _, err := latest.Restore(false); !errors.As(err, &modified) || len(modified.Paths) != 1 {
+		t.Fatalf("expected ModifiedError for one file, got %v", err)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "edited\n" {
+		t.Errorf("expected modified file untouched, got %q", got)
+	}
+	if _, err := latest.Restore(true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "a  \n" {
+		t.Errorf("expected forced restore, got %q", got)
+	}
+}
+
+func TestClose_EmptySetLeavesNothing(t *testing.T) {
+	root := t.TempDir()
+	s := New(root, time.Now())
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.Dir()); !os.IsNotExist(err) {
+		t.Errorf("expected no backup directory, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	Windows1252 = "windows-1252"
+	Latin1      = "iso-8859-1"
+)
+
+var (
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// windows1252 maps 0x80-0x9F; zero entries are undefined in the code page.
+var windows1252 = [32]rune{
+	0x20AC, 0, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017D, 0,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0, 0x017E, 0x0178,
+}
+
+func Detect(b []byte) (string, bool) {
+	if enc, ok := DetectUTF16(b); ok {
+		return enc, true
+	}
+	if utf8.Valid(b) {
+		return UTF8, true
+	}
+	return detectLegacy(b)
+}
+
+func DetectUTF16(b []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(b, bomUTF16LE):
+		return UTF16LE, true
+	case bytes.HasPrefix(b, bomUTF16BE):
+		return UTF16BE, true
+	}
+	return "", false
+}
+
+// InvalidUTF8 returns the offset of the first invalid byte when b is not
+// valid UTF-8 but does contain valid multibyte sequences. Such content is
+// damaged UTF-8 rather than a legacy encoding and must not be converted.
+func InvalidUTF8(b []byte) (int, bool) {
+	bad, multibyte := -1, false
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			if bad < 0 {
+				bad = i
+			}
+		case size > 1:
+			multibyte = true
+		}
+		i += size
+	}
+	return bad, bad >= 0 && multibyte
+}
+
+func detectLegacy(b []byte) (string, bool) {
+	if _, ok := InvalidUTF8(b); ok {
+		return "", false
+	}
+	enc := Latin1
+	for _, c := range b {
+		switch {
+		case c == 0 || c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f':
+			return "", false
+		case c >= 0x80 && c < 0xA0:
+			if windows1252[c-0x80] == 0 {
+				return "", false
+			}
+			enc = Windows1252
+		}
+	}
+	return enc, true
+}
+
+func ToUTF8(b []byte, enc string) ([]byte, error) {
+	switch enc {
+	case UTF8:
+		return b, nil
+	case UTF16LE, UTF16BE:
+		return decodeUTF16(b, enc)
+	case Windows1252, Latin1:
+		out := make([]byte, 0, len(b)+len(b)/4)
+		for _, c := range b {
+			r := rune(c)
+			if enc == Windows1252 && c >= 0x80 && c < 0xA0 {
+				if r = windows1252[c-0x80]; r == 0 {
+					return nil, fmt.Errorf("byte 0x%02X is undefined in %s", c, enc)
+				}
+			}
+			out = utf8.AppendRune(out, r)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", enc)
+}
+
+func decodeUTF16(b []byte, enc string) ([]byte, error) {
+	if len(b)%2 != 0 {
+		return nil, fmt.Errorf("%s content has an odd number of bytes", enc)
+	}
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		if enc == UTF16LE {
+			units = append(units, uint16(b[i])|uint16(b[i+1])<<8)
+		} else {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+	}
+	if len(units) > 0 && units[0] == 0xFEFF {
+		units = units[1:]
+	}
+	out := make([]byte, 0, len(units))
+	for i := 0; i < len(units); i++ {
+		r := rune(units[i])
+		if utf16.IsSurrogate(r) {
+			if i+1 < len(units) {
+				r = utf16.DecodeRune(r, rune(units[i+1]))
+			} else {
+				r = utf8.RuneError
+			}
+			if r == utf8.RuneError {
+				return nil, fmt.Errorf("%s content contains an unpaired surrogate", enc)
+			}
+			i++
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
@@ -0,0 +1,59 @@
+package charset
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+		ok   bool
+	}{
+		{"utf-8", []byte("caf\xc3\xa9\n"), UTF8, true},
+		{"utf-16le bom", []byte{0xFF, 0xFE, 'a', 0, '\n', 0}, UTF16LE, true},
+		{"utf-16be bom", []byte{0xFE, 0xFF, 0, 'a', 0, '\n'}, UTF16BE, true},
+		{"windows-1252", []byte("\x93quoted\x94 caf\xe9\r\n"), Windows1252, true},
+		{"latin-1", []byte("caf\xe9\n"), Latin1, true},
+		{"undefined in 1252", []byte{0x80, 0x81, 0x82}, "", false},
+		{"binary", []byte("\xe9\x00\x01"), "", false},
+		{"utf-8 with a stray byte", []byte("caf\xc3\xa9 r\xe9sum\xc3\xa9\n"), "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Detect(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: Detect = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		enc  string
+		want string
+	}{
+		{[]byte("\x93hi\x94 \x80\r\n"), Windows1252, "\u201chi\u201d \u20ac\r\n"},
+		{[]byte("caf\xe9\n"), Latin1, "caf\u00e9\n"},
+		{[]byte{0xFF, 0xFE, 'h', 0, 0xE9, 0, '\r', 0, '\n', 0}, UTF16LE, "h\u00e9\r\n"},
+		{[]byte{0xFE, 0xFF, 0xD8, 0x3D, 0xDE, 0x00, 0, '\n'}, UTF16BE, "\U0001F600\n"},
+	}
+	for _, tt := range tests {
+		got, err := ToUTF8(tt.in, tt.enc)
+		if err != nil {
+			t.Errorf("%s: %v", tt.enc, err)
+			continue
+		}
+		if !bytes.Equal(got, []byte(tt.want)) {
+			t.Errorf("%s: got %q, want %q", tt.enc, got, tt.want)
+		}
+	}
+}
+
+func TestToUTF8_UnpairedSurrogate(t *testing.T) {
+	if _, err := ToUTF8([]byte{0xFF, 0xFE, 0x3D, 0xD8, 'a', 0}, UTF16LE); err == nil {
+		t.Error("expected error for unpaired surrogate")
+	}
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"prosefmt/internal/norm"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const FileName = ".prosefmt.json"
+
+type Config struct {
+	Only      []string              `json:"only,omitempty"`
+	Skip      []string              `json:"skip,omitempty"`
+	Rules     map[string]RuleConfig `json:"rules,omitempty"`
+	Overrides []Override            `json:"overrides,omitempty"`
+	Plugins   []Plugin              `json:"plugins,omitempty"`
+	Custom    []CustomRule          `json:"customRules,omitempty"`
+	Dir       string                `json:"-"`
+}
+
+type CustomRule struct {
+	ID          string   `json:"id"`
+	Message     string   `json:"message"`
+	Pattern     string   `json:"pattern"`
+	Replacement *string  `json:"replacement,omitempty"`
+	Files       []string `json:"files,omitempty"`
+	Types       []string `json:"types,omitempty"`
+	Severity    string   `json:"severity,omitempty"`
+}
+
+type Plugin struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description,omitempty"`
+	Command     []string `json:"command"`
+	Files       []string `json:"files,omitempty"`
+	Types       []string `json:"types,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"`
+}
+
+type Override struct {
+	Files []string              `json:"files,omitempty"`
+	Types []string              `json:"types,omitempty"`
+	Rules map[string]RuleConfig `json:"rules"`
+}
+
+type RuleConfig struct {
+	Enabled    *bool    `json:"enabled,omitempty"`
+	Allow      []string `json:"allow,omitempty"`
+	Typography *bool    `json:"typography,omitempty"`
+	Form       string   `json:"form,omitempty"`
+	Max        int      `json:"max,omitempty"`
+	Severity   string   `json:"severity,omitempty"`
+}
+
+type RuleSet map[string]RuleConfig
+
+func (rs RuleSet) Get(id string) RuleConfig {
+	return rs[id]
+}
+
+func (rs RuleSet) Enabled(id string, def bool) bool {
+	rc, ok := rs[id]
+	if !ok || rc.Enabled == nil {
+		return def
+	}
+	return *rc.Enabled
+}
+
+func (rc RuleConfig) merge(o RuleConfig) RuleConfig {
+	if o.Enabled != nil {
+		rc.Enabled = o.Enabled
+	}
+	if o.Allow != nil {
+		rc.Allow = o.Allow
+	}
+	if o.Typography != nil {
+		rc.Typography = o.Typography
+	}
+	if o.Form != "" {
+		rc.Form = o.Form
+	}
+	if o.Max != 0 {
+		rc.Max = o.Max
+	}
+	if o.Severity != "" {
+		rc.Severity = o.Severity
+	}
+	return rc
+}
+
+func Load(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if err := validateRules(c.Rules); err != nil {
+		return nil, fmt.Errorf("%s: rules: %w", file, err)
+	}
+	for i, o := range c.Overrides {
+		if err := validateRules(o.Rules); err != nil {
+			return nil, fmt.Errorf("%s: overrides[%d]: %w", file, i, err)
+		}
+		if len(o.Files) == 0 && len(o.Types) == 0 {
+			return nil, fmt.Errorf("%s: overrides[%d]: files or types must not be empty", file, i)
+		}
+		for _, g := range o.Files {
+			if _, err := path.Match(g, ""); err != nil {
+				return nil, fmt.Errorf("%s: overrides[%d]: bad pattern %q", file, i, g)
+			}
+		}
+	}
+	seen := make(map[string]bool)
+	for i, p := range c.Plugins {
+		if p.ID == "" || len(p.Command) == 0 {
+			return nil, fmt.Errorf("%s: plugins[%d]: id and command must not be empty", file, i)
+		}
+		if seen[strings.ToUpper(p.ID)] {
+			return nil, fmt.Errorf("%s: plugins[%d]: duplicate id %q", file, i, p.ID)
+		}
+		seen[strings.ToUpper(p.ID)] = true
+		if p.Timeout != "" {
+			if _, err := time.ParseDuration(p.Timeout); err != nil {
+				return nil, fmt.Errorf("%s: plugins[%d]: bad timeout %q", file, i, p.Timeout)
+			}
+		}
+		for _, g := range p.Files {
+			if _, err := path.Match(g, ""); err != nil {
+				return nil, fmt.Errorf("%s: plugins[%d]: bad pattern %q", file, i, g)
+			}
+		}
+	}
+	for i, r := range c.Custom {
+		if r.ID == "" || r.Message == "" || r.Pattern == "" {
+			return nil, fmt.Errorf("%s: customRules[%d]: id, message and pattern must not be empty", file, i)
+		}
+		if seen[strings.ToUpper(r.ID)] {
+			return nil, fmt.Errorf("%s: customRules[%d]: duplicate id %q", file, i, r.ID)
+		}
+		seen[strings.ToUpper(r.ID)] = true
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return nil, fmt.Errorf("%s: customRules[%d]: %w", file, i, err)
+		}
+		if err := validateSeverity(r.Severity); err != nil {
+			return nil, fmt.Errorf("%s: customRules[%d]: %w", file, i, err)
+		}
+		for _, g := range r.Files {
+			if _, err := path.Match(g, ""); err != nil {
+				return nil, fmt.Errorf("%s: customRules[%d]: bad pattern %q", file, i, g)
+			}
+		}
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	c.Dir = filepath.Dir(abs)
+	return &c, nil
+}
+
+func validateRules(rules map[string]RuleConfig) error {
+	for id, rc := range rules {
+		if _, err := norm.ParseForm(rc.Form); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		if err := validateSeverity(rc.Severity); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func validateSeverity(s string) error {
+	switch s {
+	case "", "info", "warning", "error":
+		return nil
+	}
+	return fmt.Errorf("unknown severity %q (want info, warning or error)", s)
+}
+
+func Find(start string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+	for {
+		p := filepath.Join(dir, FileName)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func (c *Config) For(file, fileType string) RuleSet {
+	rs := make(RuleSet)
+	if c == nil {
+		return rs
+	}
+	for id, rc := range c.Rules {
+		rs[id] = rc
+	}
+	rel := c.rel(file)
+	for _, o := range c.Overrides {
+		if !o.matches(rel, fileType) {
+			continue
+		}
+		for id, rc := range o.Rules {
+			rs[id] = rs[id].merge(rc)
+		}
+	}
+	return rs
+}
+
+func (c *Config) rel(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil || c.Dir == "" {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(c.Dir, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (c *Config) Matches(files, types []string, file, fileType string) bool {
+	return Override{Files: files, Types: types}.matches(c.rel(file), fileType)
+}
+
+func (o Override) matches(rel, fileType string) bool {
+	if len(o.Files) > 0 && !matchAny(o.Files, rel) {
+		return false
+	}
+	if len(o.Types) > 0 && !contains(o.Types, fileType) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(globs []string, rel string) bool {
+	for _, g := range globs {
+		if Match(g, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func Match(glob, rel string) bool {
+	if !strings.Contains(glob, "/") {
+		ok, _ := path.Match(glob, path.Base(rel))
+		return ok
+	}
+	glob = strings.TrimPrefix(glob, "./")
+	if strings.HasPrefix(glob, "**/") {
+		rest := glob[len("**/"):]
+		parts := strings.Split(rel, "/")
+		for i := range parts {
+			if ok, _ := path.Match(rest, strings.Join(parts[i:], "/")); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.HasSuffix(glob, "/**") {
+		prefix := strings.TrimSuffix(glob, "/**")
+		return rel == prefix || strings.HasPrefix(rel, prefix+"/")
+	}
+	ok, _ := path.Match(glob, rel)
+	return ok
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_OverridesMergeByGlob(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, FileName)
+	data := `{
+  "rules": {"TL011": {"enabled": true, "allow": ["U+00A0"]}},
+  "overrides": [
+    {"files": ["*.md"], "rules": {"TL011": {"typography": true}}},
+    {"files": ["docs/fr/**"], "rules": {"TL011": {"allow": ["U+202F"]}}}
+  ]
+}`
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := c.For(filepath.Join(dir, "a.txt"), "text")
+	if !txt.Enabled("TL011", false) || txt.Get("TL011").Typography != nil {
+		t.Errorf("a.txt: expected base settings only, got %+v", txt.Get("TL011"))
+	}
+	md := c.For(filepath.Join(dir, "docs", "fr", "guide.md"), "markdown").Get("TL011")
+	if md.Typography == nil || !*md.Typography {
+		t.Errorf("guide.md: expected typography from *.md override, got %+v", md)
+	}
+	if len(md.Allow) != 1 || md.Allow[0] != "U+202F" {
+		t.Errorf("guide.md: expected allow replaced by docs/fr/** override, got %v", md.Allow)
+	}
+}
+
+func TestLoad_RejectsEmptyOverrideFiles(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(file, []byte(`{"overrides": [{"rules": {}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil {
+		t.Error("expected error for override without files or types")
+	}
+}
+
+func TestFind_WalksUp(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, FileName)
+	if err := os.WriteFile(file, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	found, err := Find(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != file {
+		t.Errorf("expected %q, got %q", file, found)
+	}
+}
+
+func TestNilConfig_DefaultRuleSet(t *testing.T) {
+	var c *Config
+	if !c.For("x.txt", "text").Enabled("TL001", true) {
+		t.Error("expected default enabled for nil config")
+	}
+}
+
+func TestLoad_RejectsUnknownForm(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(file, []byte(`{"rules": {"TL020": {"form": "NFKC"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil {
+		t.Error("expected error for unsupported normalization form")
+	}
+}
+
+func TestFor_MatchesTypes(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, FileName)
+	data := `{"overrides": [
+  {"types": ["makefile"], "rules": {"TL010": {"enabled": false}}},
+  {"files": ["*.md"], "types": ["text"], "rules": {"TL011": {"enabled": true}}}
+]}`
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.For(filepath.Join(dir, "Makefile"), "makefile").Enabled("TL010", true) {
+		t.Error("expected TL010 disabled for makefile type")
+	}
+	if !c.For(filepath.Join(dir, "build.mk"), "text").Enabled("TL010", true) {
+		t.Error("expected TL010 enabled for non-makefile type")
+	}
+	if c.For(filepath.Join(dir, "a.md"), "markdown").Enabled("TL011", false) {
+		t.Error("expected override with files and types to require both")
+	}
+}
+
+func TestLoad_RejectsUnknownSeverity(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(file, []byte(`{"rules": {"TL010": {"severity": "fatal"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestLoad_Plugins(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(file, []byte(`{"plugins": [{"id": "HOUSE001", "command": ["./check.sh"], "files": ["*.md"], "timeout": "5s"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Matches(c.Plugins[0].Files, nil, filepath.Join(c.Dir, "docs", "a.md"), "markdown") {
+		t.Error("expected plugin files to match")
+	}
+	for _, bad := range []string{
+		`{"plugins": [{"id": "HOUSE001"}]}`,
+		`{"plugins": [{"id": "A", "command": ["x"]}, {"id": "a", "command": ["y"]}]}`,
+		`{"plugins": [{"id": "A", "command": ["x"], "timeout": "soon"}]}`,
+	} {
+		if err := os.WriteFile(file, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(file); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestLoad_CustomRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FileName)
+	for _, bad := range []string{
+		`{"customRules": [{"id": "S1", "message": "m", "pattern": "("}]}`,
+		`{"customRules": [{"id": "S1", "pattern": "x"}]}`,
+		`{"customRules": [{"id": "S1", "message": "m", "pattern": "x", "severity": "fatal"}]}`,
+		`{"customRules": [{"id": "S1", "message": "m", "pattern": "x"}], "plugins": [{"id": "s1", "command": ["x"]}]}`,
+	} {
+		if err := os.WriteFile(file, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(file); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+	if err := os.WriteFile(file, []byte(`{"customRules": [{"id": "S1", "message": "m", "pattern": "x", "replacement": ""}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Custom[0].Replacement == nil || *c.Custom[0].Replacement != "" {
+		t.Error("expected an empty replacement to be kept")
+	}
+}
@@ -0,0 +1,100 @@
+package custom
+
+import (
+	"bytes"
+	"fmt"
+	"prosefmt/internal/config"
+	"prosefmt/internal/rules"
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	config.CustomRule
+	cfg *config.Config
+	re  *regexp.Regexp
+}
+
+// Rules returns a rule for each custom rule in cfg.
+func Rules(cfg *config.Config) ([]rules.Rule, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	var out []rules.Rule
+	for _, c := range cfg.Custom {
+		re, err := regexp.Compile("(?m)" + c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("custom rule %s: %w", c.ID, err)
+		}
+		r := &rule{CustomRule: c, cfg: cfg, re: re}
+		ir := rules.Rule{
+			ID:             strings.ToUpper(c.ID),
+			Description:    c.Message,
+			DefaultEnabled: true,
+			Severity:       rules.Severity(c.Severity),
+			Check:          r.check,
+		}
+		if c.Replacement != nil {
+			ir.Fix = r.fix
+		}
+		out = append(out, ir)
+	}
+	return out, nil
+}
+
+func (r *rule) applies(file string, opts rules.Options) bool {
+	if len(r.Files) == 0 && len(r.Types) == 0 {
+		return true
+	}
+	return r.cfg.Matches(r.Files, r.Types, file, opts.Type)
+}
+
+func (r *rule) check(file string, content []byte, opts rules.Options) []rules.Issue {
+	if !r.applies(file, opts) {
+		return nil
+	}
+	var issues []rules.Issue
+	line, lineStart, pos := 1, 0, 0
+	advance := func(to int) {
+		for pos < to {
+			i := bytes.IndexByte(content[pos:to], '\n')
+			if i < 0 {
+				pos = to
+				break
+			}
+			pos += i + 1
+			line++
+			lineStart = pos
+		}
+	}
+	for _, m := range r.re.FindAllSubmatchIndex(content, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		advance(m[0])
+		issue := rules.Issue{File: file, Line: line, Column: m[0] - lineStart + 1, RuleID: strings.ToUpper(r.ID), Message: r.Message}
+		if n := bytes.Count(content[m[0]:m[1]-1], []byte("\n")); n > 0 {
+			issue.EndLine = line + n
+		}
+		if r.Replacement != nil {
+			issue.Edit = &rules.TextEdit{Start: m[0], End: m[1], NewText: string(r.re.Expand(nil, []byte(*r.Replacement), content, m))}
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+func (r *rule) fix(content []byte, opts rules.Options) []byte {
+	if !r.applies(opts.File, opts) {
+		return content
+	}
+	var edits []rules.TextEdit
+	for _, i := range r.check(opts.File, content, opts) {
+		edits = append(edits, *i.Edit)
+	}
+	out, err := rules.ApplyEdits(content, edits)
+	if err != nil {
+		return content
+	}
+	return out
+}
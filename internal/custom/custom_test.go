@@ -0,0 +1,127 @@
+package custom
+
+import (
+	"path/filepath"
+	"prosefmt/internal/config"
+	"prosefmt/internal/rules"
+	"testing"
+)
+
+func customIssues(file string, content []byte, opts rules.Options, id string) []rules.Issue {
+	var out []rules.Issue
+	for _, i := range rules.CheckWith(file, content, opts) {
+		if i.RuleID == id {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func TestCustomRule_CheckAndFix(t *testing.T) {
+	repl := "e-mail"
+	cfg := &config.Config{Dir: t.TempDir(), Custom: []config.CustomRule{{
+		ID:          "style001",
+		Message:     "write e-mail",
+		Pattern:     `\b[Ee]mail\b`,
+		Replacement: &repl,
+		Files:       []string{"*.md"},
+		Severity:    "warning",
+	}}}
+	extra, err := Rules(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := filepath.Join(cfg.Dir, "a.md")
+	content := []byte("Send email\nor\tan email or Email.\n")
+	opts := rules.Options{File: md, Extra: extra}
+	got := customIssues(md, content, opts, "STYLE001")
+	want := [][2]int{{1, 6}, {2, 7}, {2, 16}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), got)
+	}
+	for k, w := range want {
+		if got[k].Line != w[0] || got[k].Column != w[1] || got[k].Severity != rules.SeverityWarning {
+			t.Errorf("issue %d: got %d:%d %s, want %d:%d warning", k, got[k].Line, got[k].Column, got[k].Severity, w[0], w[1])
+		}
+	}
+	if out := rules.FixWith(content, opts); string(out) != "Send e-mail\nor\tan e-mail or e-mail.\n" {
+		t.Errorf("unexpected fix %q", out)
+	}
+	txt := filepath.Join(cfg.Dir, "a.txt")
+	if got := customIssues(txt, content, rules.Options{File: txt, Extra: extra}, "STYLE001"); len(got) != 0 {
+		t.Errorf("rule ran outside its files: %v", got)
+	}
+	opts.Rules = config.RuleSet{"STYLE001": {Severity: "info"}}
+	if got := customIssues(md, content, opts, "STYLE001"); got[0].Severity != rules.SeverityInfo {
+		t.Errorf("config severity must override the rule default, got %s", got[0].Severity)
+	}
+}
+
+func TestCustomRule_MultiLine(t *testing.T) {
+	cfg := &config.Config{Dir: t.TempDir(), Custom: []config.CustomRule{
+		{ID: "STYLE002", Message: "no empty list item", Pattern: `^- *\n- `},
+		{ID: "STYLE003", Message: "no TODO lines", Pattern: `^TODO.*$`},
+	}}
+	extra, err := Rules(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := rules.Options{Extra: extra}
+	content := []byte("intro\n-\n- b\nx TODO\nTODO later\n")
+	got := customIssues("a.txt", content, opts, "STYLE002")
+	if len(got) != 1 || got[0].Line != 2 || got[0].Column != 1 || got[0].EndLine != 3 {
+		t.Errorf("expected one match over lines 2-3, got %v", got)
+	}
+	got = customIssues("a.txt", content, opts, "STYLE003")
+	if len(got) != 1 || got[0].Line != 5 || got[0].EndLine != 0 {
+		t.Errorf("expected ^ and $ to match per line, got %v", got)
+	}
+	if out := rules.FixWith(content, opts); string(out) != string(content) {
+		t.Errorf("rules without replacement must not fix, got %q", out)
+	}
+}
+
+func TestRules_ScopedToConfig(t *testing.T) {
+	a := &config.Config{Dir: t.TempDir(), Custom: []config.CustomRule{{ID: "STYLE004", Message: "no foo", Pattern: `foo`}}}
+	b := &config.Config{Dir: t.TempDir(), Custom: []config.CustomRule{{ID: "STYLE004", Message: "no bar", Pattern: `bar`}}}
+	ra, err := Rules(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, err := Rules(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("foo bar\n")
+	if got := customIssues("a.txt", content, rules.Options{Extra: ra}, "STYLE004"); len(got) != 1 || got[0].Message != "no foo" {
+		t.Errorf("config a: got %v", got)
+	}
+	if got := customIssues("a.txt", content, rules.Options{Extra: rb}, "STYLE004"); len(got) != 1 || got[0].Message != "no bar" {
+		t.Errorf("config b: got %v", got)
+	}
+	if got := customIssues("a.txt", content, rules.Options{}, "STYLE004"); len(got) != 0 {
+		t.Errorf("custom rules must not be registered globally, got %v", got)
+	}
+}
+
+func TestCustomRule_ZeroLengthMatches(t *testing.T) {
+	repl := "y"
+	cfg := &config.Config{Dir: t.TempDir(), Custom: []config.CustomRule{{ID: "STYLE005", Message: "no x", Pattern: `x*`, Replacement: &repl}}}
+	extra, err := Rules(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := rules.Options{Extra: extra}
+	content := []byte("axxb x\n")
+	got := customIssues("a.txt", content, opts, "STYLE005")
+	if len(got) != 2 || got[0].Column != 2 || got[1].Column != 6 {
+		t.Fatalf("expected the two non-empty matches, got %v", got)
+	}
+	out, err := rules.FixChecked("a.txt", content, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "ayb y\n" {
+		t.Errorf("expected only the non-empty matches replaced, got %q", out)
+	}
+}
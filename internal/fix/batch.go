@@ -0,0 +1,151 @@
+package fix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"prosefmt/internal/rules"
+	"sort"
+)
+
+type Batch struct {
+	staged []staged
+}
+
+type staged struct {
+	path     string
+	tmp      string
+	backup   string
+	original []byte
+}
+
+func (b *Batch) Stage(path string, opts rules.Options) (bool, error) {
+	content, out, err := fixFile(path, opts)
+	if err != nil || bytes.Equal(out, content) {
+		return false, err
+	}
+	tmp, err := writeTemp(path, out)
+	if err != nil {
+		return false, err
+	}
+	b.staged = append(b.staged, staged{path: path, tmp: tmp, original: content})
+	if back, err := os.ReadFile(tmp); err != nil || !bytes.Equal(back, out) {
+		if err == nil {
+			err = fmt.Errorf("%s: staged content does not match", path)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *Batch) Paths() []string {
+	paths := make([]string, 0, len(b.staged))
+	for _, s := range b.staged {
+		paths = append(paths, s.path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (b *Batch) Abort() {
+	for _, s := range b.staged {
+		os.Remove(s.tmp)
+	}
+	b.staged = nil
+}
+
+func (b *Batch) Commit() error {
+	defer b.Abort()
+	for _, s := range b.staged {
+		current, err := os.ReadFile(s.path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(current, s.original) {
+			return fmt.Errorf("%s: modified since it was staged", s.path)
+		}
+	}
+	for i := range b.staged {
+		s := &b.staged[i]
+		backup, err := backupFile(s.path)
+		if err != nil {
+			return b.rollback(err)
+		}
+		s.backup = backup
+		if err := os.Rename(s.tmp, s.path); err != nil {
+			return b.rollback(err)
+		}
+	}
+	for _, s := range b.staged {
+		os.Remove(s.backup)
+	}
+	return nil
+}
+
+func (b *Batch) rollback(cause error) error {
+	var errs []error
+	for _, s := range b.staged {
+		if s.backup == "" {
+			continue
+		}
+		if err := os.Rename(s.backup, s.path); err != nil {
+			errs = append(errs, fmt.Errorf("restore %s: %w", s.path, err))
+			continue
+		}
+		os.Remove(s.backup)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w (rollback failed: %w)", cause, errors.Join(errs...))
+	}
+	return fmt.Errorf("%w (rolled back)", cause)
+}
+
+func backupFile(path string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), ".prosefmt-backup-*")
+	if err != nil {
+		return "", err
+	}
+	backup := f.Name()
+	f.Close()
+	os.Remove(backup)
+	if err := os.Link(path, backup); err == nil {
+		return backup, nil
+	}
+	if err := copyFile(path, backup); err != nil {
+		os.Remove(backup)
+		return "", err
+	}
+	return backup, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if err := out.Chmod(fi.Mode().Perm()); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
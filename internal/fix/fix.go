@@ -1,41 +1,92 @@
 package fix
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"prosefmt/internal/rules"
+	"prosefmt/internal/scanner"
 )
 
 func Apply(path string) error {
+	_, err := ApplyWith(path, rules.Options{})
+	return err
+}
+
+func ApplyWith(path string, opts rules.Options) (bool, error) {
+	return Rewrite(path, func(content []byte) ([]byte, error) {
+		return rules.FixChecked(path, content, opts)
+	})
+}
+
+func Rewrite(path string, edit func(content []byte) ([]byte, error)) (bool, error) {
+	content, out, err := editFile(path, edit)
+	if err != nil || bytes.Equal(out, content) {
+		return false, err
+	}
+	if err := writeAtomic(path, out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func fixFile(path string, opts rules.Options) ([]byte, []byte, error) {
+	return editFile(path, func(content []byte) ([]byte, error) {
+		return rules.FixChecked(path, content, opts)
+	})
+}
+
+func editFile(path string, edit func(content []byte) ([]byte, error)) ([]byte, []byte, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	out := rules.Fix(content)
-	if err := writeAtomic(path, out); err != nil {
-		return err
+	if _, err := scanner.Validate(content); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
 	}
-	return nil
+	out, err := edit(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return content, out, nil
 }
 
 func writeAtomic(path string, data []byte) error {
-	dir := filepath.Dir(path)
-	f, err := os.CreateTemp(dir, ".prosefmt-*")
+	tmp, err := writeTemp(path, data)
 	if err != nil {
 		return err
 	}
-	tmp := f.Name()
 	defer os.Remove(tmp)
+	return os.Rename(tmp, path)
+}
+
+func writeTemp(path string, data []byte) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), ".prosefmt-*")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+	if fi, err := os.Stat(path); err == nil {
+		if err := f.Chmod(fi.Mode().Perm()); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return "", err
+		}
+	}
 	if _, err := f.Write(data); err != nil {
 		f.Close()
-		return err
+		os.Remove(tmp)
+		return "", err
 	}
 	if err := f.Sync(); err != nil {
 		f.Close()
-		return err
+		os.Remove(tmp)
+		return "", err
 	}
 	if err := f.Close(); err != nil {
-		return err
+		os.Remove(tmp)
+		return "", err
 	}
-	return os.Rename(tmp, path)
+	return tmp, nil
 }
@@ -1,9 +1,13 @@
 package fix
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"prosefmt/internal/config"
 	"prosefmt/internal/rules"
+	"prosefmt/internal/scanner"
+	"strings"
 	"testing"
 )
 
@@ -33,3 +37,163 @@ func TestApply(t *testing.T) {
 		t.Errorf("fixed file should have no issues, got %v", issues)
 	}
 }
+
+func TestApply_RefusesBinaryTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mixed.txt")
+	content := append(bytes.Repeat([]byte("text  \n"), 10000), 0x00, 0xFF, 0x10)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := Apply(path)
+	if !scanner.IsNotText(err) {
+		t.Fatalf("expected not-text error, got %v", err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, content) {
+		t.Error("expected file with binary tail to be left untouched")
+	}
+}
+
+func TestApply_KeepsFileMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(path, []byte("echo hi  \n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(path); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755 kept, got %v (%v)", fi.Mode(), err)
+	}
+	backup := filepath.Join(dir, "copy.sh")
+	if err := copyFile(path, backup); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(backup); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("expected copied mode 0755, got %v (%v)", fi.Mode(), err)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("%s: got %q, want %q", filepath.Base(path), got, want)
+	}
+}
+
+func assertOnly(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(names) {
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name())
+		}
+		t.Errorf("expected only %v in %s, got %v", names, dir, got)
+	}
+}
+
+func TestBatch_Commit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "a  \n", "b.txt": "b\n\n", "c.txt": "c\n"})
+	var b Batch
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if _, err := b.Stage(filepath.Join(dir, name), rules.Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(b.Paths()) != 2 {
+		t.Errorf("expected 2 staged files, got %v", b.Paths())
+	}
+	assertContent(t, filepath.Join(dir, "a.txt"), "a  \n")
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(t, filepath.Join(dir, "a.txt"), "a\n")
+	assertContent(t, filepath.Join(dir, "b.txt"), "b\n")
+	assertOnly(t, dir, "a.txt", "b.txt", "c.txt")
+}
+
+func TestBatch_RollbackOnFailedRename(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "a  \n", "b.txt": "b  \n"})
+	var b Batch
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := b.Stage(filepath.Join(dir, name), rules.Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Remove(b.staged[1].tmp)
+	err := b.Commit()
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected rolled back error, got %v", err)
+	}
+	assertContent(t, filepath.Join(dir, "a.txt"), "a  \n")
+	assertContent(t, filepath.Join(dir, "b.txt"), "b  \n")
+	assertOnly(t, dir, "a.txt", "b.txt")
+}
+
+func TestBatch_RefusesFilesModifiedAfterStaging(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "a  \n", "b.txt": "b  \n"})
+	var b Batch
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := b.Stage(filepath.Join(dir, name), rules.Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFiles(t, dir, map[string]string{"b.txt": "edited  \n"})
+	if err := b.Commit(); err == nil {
+		t.Fatal("expected error for a file modified after staging")
+	}
+	assertContent(t, filepath.Join(dir, "a.txt"), "a  \n")
+	assertContent(t, filepath.Join(dir, "b.txt"), "edited  \n")
+	assertOnly(t, dir, "a.txt", "b.txt")
+}
+
+func TestApply_RefusesFixThatDoesNotConverge(t *testing.T) {
+	swap := strings.NewReplacer("ab", "ba", "ba", "ab")
+	x100 := rules.Rule{
+		ID:    "X100",
+		Check: func(string, []byte, rules.Options) []rules.Issue { return nil },
+		Fix:   func(content []byte, _ rules.Options) []byte { return []byte(swap.Replace(string(content))) },
+	}
+	path := filepath.Join(t.TempDir(), "a.txt")
+	content := []byte("ab  \n")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	enabled := true
+	opts := rules.Options{File: path, Rules: config.RuleSet{"X100": {Enabled: &enabled}}, Extra: []rules.Rule{x100}}
+	_, err := ApplyWith(path, opts)
+	if err == nil || !strings.Contains(err.Error(), "X100: fixes do not converge") {
+		t.Errorf("expected a diagnostic naming X100, got %v", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, content) {
+		t.Errorf("file must be left untouched, got %q", after)
+	}
+	if rules.HasFixer("X100") {
+		t.Error("X100 must not be left in the rule registry")
+	}
+}
@@ -0,0 +1,275 @@
+package interactive
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"prosefmt/internal/report"
+	"prosefmt/internal/rules"
+	"strings"
+)
+
+const (
+	contextLines = 2
+
+	bold  = "\x1b[1m"
+	red   = "\x1b[31m"
+	green = "\x1b[32m"
+	cyan  = "\x1b[36m"
+	reset = "\x1b[0m"
+)
+
+const help = `y - apply this fix
+n - skip this fix
+a - apply this fix and all remaining fixes of this rule
+q - quit; do not apply this fix or any remaining ones
+? - print help
+`
+
+type Session struct {
+	in       *bufio.Reader
+	out      io.Writer
+	color    bool
+	all      map[string]bool
+	quit     bool
+	Accepted int
+	Skipped  int
+}
+
+// position is an issue by byte offset, so skipped issues can be followed
+// through the edits applied after them.
+type position struct {
+	id      string
+	offset  int
+	message string
+}
+
+func New(in io.Reader, out io.Writer, color bool) *Session {
+	return &Session{in: bufio.NewReader(in), out: out, color: color, all: make(map[string]bool)}
+}
+
+func (s *Session) Quit() bool {
+	return s.quit
+}
+
+func (s *Session) Review(file string, content []byte, opts rules.Options) ([]byte, error) {
+	skipped := make(map[position]bool)
+	// A pass ends when an edit lands at or before the previous one; fixes
+	// that keep going back, like rules.FixChecked, do not converge.
+	passes, last := 1, -1
+	for !s.quit {
+		var next *rules.Change
+		for _, c := range rules.Changes(file, content, opts) {
+			if !allSkipped(skipped, content, c) {
+				next = &c
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		accept := s.all[next.RuleID]
+		if !accept {
+			if err := s.show(file, content, *next); err != nil {
+				return content, err
+			}
+			answer, err := s.ask()
+			if err != nil {
+				return content, err
+			}
+			switch answer {
+			case 'y':
+				accept = true
+			case 'a':
+				s.all[next.RuleID] = true
+				accept = true
+			case 'q':
+				s.quit = true
+				continue
+			}
+		}
+		if !accept {
+			skip(skipped, content, *next)
+			s.Skipped++
+			continue
+		}
+		if next.Edit.Start <= last {
+			passes++
+		}
+		if passes > rules.MaxFixPasses {
+			return content, &rules.FixError{Rules: []string{next.RuleID}, Reason: fmt.Sprintf("fixes do not converge after %d passes", rules.MaxFixPasses)}
+		}
+		last = next.Edit.Start
+		out, err := rules.ApplyEdits(content, []rules.TextEdit{next.Edit})
+		if err != nil {
+			return content, err
+		}
+		if bytes.Equal(out, content) {
+			skip(skipped, content, *next)
+			continue
+		}
+		skipped = remap(skipped, next.Edit)
+		content = out
+		s.Accepted++
+	}
+	return content, nil
+}
+
+func positionOf(content []byte, i rules.Issue) position {
+	starts := lineStarts(content)
+	off := 0
+	if i.Line > 0 && i.Line <= len(starts) {
+		off = starts[i.Line-1] + i.Column - 1
+	}
+	return position{i.RuleID, off, i.Message}
+}
+
+func skip(skipped map[position]bool, content []byte, c rules.Change) {
+	for _, i := range c.Issues {
+		skipped[positionOf(content, i)] = true
+	}
+}
+
+func allSkipped(skipped map[position]bool, content []byte, c rules.Change) bool {
+	for _, i := range c.Issues {
+		if !skipped[positionOf(content, i)] {
+			return false
+		}
+	}
+	return true
+}
+
+// remap moves the skipped positions after e by the length e adds or
+// removes. Positions inside the replaced range are dropped.
+func remap(skipped map[position]bool, e rules.TextEdit) map[position]bool {
+	out := make(map[position]bool, len(skipped))
+	delta := len(e.NewText) - (e.End - e.Start)
+	for p := range skipped {
+		switch {
+		case p.offset >= e.End:
+			p.offset += delta
+		case p.offset >= e.Start:
+			continue
+		}
+		out[p] = true
+	}
+	return out
+}
+
+func (s *Session) ask() (byte, error) {
+	for {
+		fmt.Fprint(s.out, s.paint(bold, "Apply this fix [y,n,a,q,?]? "))
+		line, err := s.in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "" && errors.Is(err, io.EOF) {
+			fmt.Fprintln(s.out)
+			return 'q', nil
+		}
+		if len(answer) == 1 && strings.Contains("ynaq", answer) {
+			return answer[0], nil
+		}
+		fmt.Fprint(s.out, help)
+	}
+}
+
+func (s *Session) show(file string, content []byte, c rules.Change) error {
+	seen := make(map[string]bool)
+	for _, i := range c.Issues {
+		head := fmt.Sprintf("%s:%d:%d: %s: %s", file, i.Line, i.Column, i.RuleID, i.Message)
+		if i.Line == 0 {
+			head = fmt.Sprintf("%s: %s: %s", file, i.RuleID, i.Message)
+		}
+		if seen[head] {
+			continue
+		}
+		seen[head] = true
+		if _, err := fmt.Fprintln(s.out, s.paint(bold, head)); err != nil {
+			return err
+		}
+	}
+	starts := lineStarts(content)
+	first, last := lineAt(starts, c.Edit.Start), lineAt(starts, c.Edit.End)
+	if c.Edit.End > c.Edit.Start && c.Edit.End == starts[last] && last > first {
+		last--
+	}
+	from, to := starts[first], lineEnd(starts, last, len(content))
+	before := content[from:to]
+	after := []byte(string(content[from:c.Edit.Start]) + c.Edit.NewText + string(content[c.Edit.End:to]))
+	if _, err := fmt.Fprintln(s.out, s.paint(cyan, fmt.Sprintf("@@ line %d @@", first+1))); err != nil {
+		return err
+	}
+	for k := max(0, first-contextLines); k < first; k++ {
+		s.line(" ", "", content[starts[k]:starts[k+1]])
+	}
+	for _, raw := range displayLines(before) {
+		s.line("-", red, raw)
+	}
+	for _, raw := range displayLines(after) {
+		s.line("+", green, raw)
+	}
+	for k := last + 1; k < len(starts) && k <= last+contextLines; k++ {
+		s.line(" ", "", content[starts[k]:lineEnd(starts, k, len(content))])
+	}
+	return nil
+}
+
+func (s *Session) line(prefix, color string, raw []byte) {
+	if len(raw) == 0 {
+		return
+	}
+	text := strings.TrimRight(string(raw), "\r\n")
+	fmt.Fprintln(s.out, s.paint(color, prefix+" "+report.Visible([]byte(text))))
+	if prefix != " " && raw[len(raw)-1] != '\n' {
+		fmt.Fprintln(s.out, s.paint(color, `\ No newline at end of file`))
+	}
+}
+
+func (s *Session) paint(color, text string) string {
+	if !s.color || color == "" {
+		return text
+	}
+	return color + text + reset
+}
+
+func lineStarts(content []byte) []int {
+	starts := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func lineAt(starts []int, off int) int {
+	k := 0
+	for k+1 < len(starts) && starts[k+1] <= off {
+		k++
+	}
+	return k
+}
+
+func lineEnd(starts []int, k, size int) int {
+	if k+1 < len(starts) {
+		return starts[k+1]
+	}
+	return size
+}
+
+func displayLines(b []byte) [][]byte {
+	var lines [][]byte
+	for len(b) > 0 {
+		n := bytes.IndexByte(b, '\n') + 1
+		if n == 0 {
+			n = len(b)
+		}
+		lines = append(lines, b[:n])
+		b = b[n:]
+	}
+	return lines
+}
@@ -0,0 +1,93 @@
+package interactive
+
+import (
+	"bytes"
+	"prosefmt/internal/config"
+	"prosefmt/internal/rules"
+	"strings"
+	"testing"
+)
+
+func review(t *testing.T, answers, content string, opts rules.Options) (string, string, *Session) {
+	t.Helper()
+	var out bytes.Buffer
+	s := New(strings.NewReader(answers), &out, false)
+	got, err := s.Review("a.txt", []byte(content), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(got), out.String(), s
+}
+
+func TestReview_AcceptAndSkip(t *testing.T) {
+	got, out, s := review(t, "n\ny\ny\n", "one  \ntwo\t\nthree", rules.Options{})
+	if got != "one  \ntwo\nthree\n" {
+		t.Errorf("got %q", got)
+	}
+	if s.Accepted != 2 || s.Skipped != 1 || s.Quit() {
+		t.Errorf("unexpected counts %d/%d quit=%v", s.Accepted, s.Skipped, s.Quit())
+	}
+	for _, want := range []string{
+		"a.txt:1:4: TL010: no trailing spaces at end of line\n@@ line 1 @@\n- one\u00b7\u00b7\n+ one\n  two\u2192\n  three\n",
+		"- three\n\\ No newline at end of file\n+ three\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestReview_AcceptAllForRule(t *testing.T) {
+	enabled := true
+	opts := rules.Options{Rules: config.RuleSet{rules.TL011ID: {Enabled: &enabled}}}
+	got, out, s := review(t, "a\nn\n", "a \nb\u00a0c \n", opts)
+	if got != "a\nb\u00a0c\n" {
+		t.Errorf("got %q", got)
+	}
+	if strings.Count(out, "Apply this fix") != 2 || s.Accepted != 2 || s.Skipped != 1 {
+		t.Errorf("expected one prompt per rule and a skipped fix to stay skipped, got:\n%s", out)
+	}
+}
+
+func TestReview_Quit(t *testing.T) {
+	got, _, s := review(t, "y\nq\n", "a \nb \n", rules.Options{})
+	if got != "a\nb \n" || !s.Quit() {
+		t.Errorf("got %q, quit=%v", got, s.Quit())
+	}
+	got, _, s = review(t, "x\n", "a \n", rules.Options{})
+	if got != "a \n" || !s.Quit() {
+		t.Errorf("end of input must quit, got %q, quit=%v", got, s.Quit())
+	}
+}
+
+func TestReview_SkipFollowsLineChanges(t *testing.T) {
+	enabled := true
+	opts := rules.Options{Rules: config.RuleSet{rules.TL011ID: {Enabled: &enabled}}}
+	got, out, s := review(t, "n\ny\n", "a\rb\u00a0c\n", opts)
+	if got != "a\nb\u00a0c\n" {
+		t.Errorf("got %q", got)
+	}
+	if strings.Count(out, "Apply this fix") != 2 || s.Accepted != 1 || s.Skipped != 1 || s.Quit() {
+		t.Errorf("expected the skipped TL011 fix not to be asked again after the line break was added, got:\n%s", out)
+	}
+}
+
+func TestReview_StopsFixThatDoesNotConverge(t *testing.T) {
+	grow := rules.Rule{
+		ID:             "X100",
+		DefaultEnabled: true,
+		Check: func(file string, content []byte, _ rules.Options) []rules.Issue {
+			if i := bytes.IndexByte(content, 'x'); i >= 0 {
+				return []rules.Issue{{File: file, Line: 1, Column: i + 1, RuleID: "X100", Message: "x", Edit: &rules.TextEdit{Start: i, End: i + 1, NewText: "xx"}}}
+			}
+			return nil
+		},
+		Fix: func(content []byte, _ rules.Options) []byte { return content },
+	}
+	var out bytes.Buffer
+	s := New(strings.NewReader("a\n"), &out, false)
+	_, err := s.Review("a.txt", []byte("x\n"), rules.Options{Extra: []rules.Rule{grow}})
+	if err == nil || !strings.Contains(err.Error(), "X100: fixes do not converge") {
+		t.Errorf("expected a diagnostic naming X100, got %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"prosefmt/internal/rules"
+	"strings"
+	"testing"
+)
+
+func TestPositions(t *testing.T) {
+	text := []byte("a\u00e9\U0001F600b\r\nx\rlast")
+	tests := []struct {
+		off int
+		pos Position
+	}{
+		{0, Position{0, 0}},
+		{3, Position{0, 2}},
+		{7, Position{0, 4}},
+		{8, Position{0, 5}},
+		{10, Position{1, 0}},
+		{12, Position{2, 0}},
+		{len(text), Position{2, 4}},
+	}
+	for _, tt := range tests {
+		if got := positionAt(text, tt.off); got != tt.pos {
+			t.Errorf("positionAt(%d) = %v, want %v", tt.off, got, tt.pos)
+		}
+		if got := offsetAt(text, tt.pos); got != tt.off {
+			t.Errorf("offsetAt(%v) = %d, want %d", tt.pos, got, tt.off)
+		}
+	}
+	if got := positionAt([]byte("a\n"), 2); got != (Position{1, 0}) {
+		t.Errorf("position after final newline = %v", got)
+	}
+}
+
+func TestApplyChange(t *testing.T) {
+	text := []byte("hello\nworld\n")
+	out := applyChange(text, contentChange{Range: &Range{Start: Position{1, 0}, End: Position{1, 5}}, Text: "there  "})
+	if string(out) != "hello\nthere  \n" {
+		t.Errorf("got %q", out)
+	}
+	if out := applyChange(text, contentChange{Text: "x"}); string(out) != "x" {
+		t.Errorf("full sync: got %q", out)
+	}
+}
+
+func TestIssueRange_ClampsBadColumns(t *testing.T) {
+	text := []byte("ab\ncd\n")
+	for _, column := range []int{0, -3, 99} {
+		r := issueRange(text, rules.Issue{Line: 1, Column: column})
+		if r.Start.Line < 0 || r.Start.Character < 0 {
+			t.Errorf("column %d: got %+v", column, r)
+		}
+	}
+}
+
+type session struct {
+	in bytes.Buffer
+	id int
+}
+
+func (s *session) request(method string, params any) {
+	s.id++
+	writeMessage(&s.in, map[string]any{"jsonrpc": "2.0", "id": s.id, "method": method, "params": params})
+}
+
+func (s *session) notify(method string, params any) {
+	writeMessage(&s.in, map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+type received struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func run(t *testing.T, s *session, configFile string) []received {
+	t.Helper()
+	return runServer(t, s, New(configFile, "test"))
+}
+
+func runServer(t *testing.T, s *session, srv *Server) []received {
+	t.Helper()
+	var out bytes.Buffer
+	if err := srv.Run(&s.in, &out); err != nil {
+		t.Fatal(err)
+	}
+	var msgs []received
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var m received
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+func TestServer_Session(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	uri := "file://" + filepath.ToSlash(path)
+	doc := map[string]any{"uri": uri}
+	s := &session{}
+	s.request("initialize", map[string]any{"rootUri": "file://" + filepath.ToSlash(dir)})
+	s.notify("initialized", map[string]any{})
+	s.notify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "version": 1, "text": "hello\nworld\n"}})
+	s.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []any{map[string]any{"range": Range{Start: Position{0, 5}, End: Position{0, 5}}, "text": "  "}},
+	})
+	diag := Diagnostic{Range: Range{Start: Position{0, 5}, End: Position{0, 7}}, Severity: severityError, Code: "TL010", Source: source, Message: "x"}
+	s.request("textDocument/codeAction", map[string]any{"textDocument": doc, "range": diag.Range, "context": map[string]any{"diagnostics": []Diagnostic{diag}}})
+	s.request("textDocument/formatting", map[string]any{"textDocument": doc, "options": map[string]any{"tabSize": 4}})
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+	msgs := run(t, s, "")
+
+	var diagnostics []publishDiagnosticsParams
+	results := make(map[int]json.RawMessage)
+	for _, m := range msgs {
+		if m.Method == "textDocument/publishDiagnostics" {
+			var p publishDiagnosticsParams
+			json.Unmarshal(m.Params, &p)
+			diagnostics = append(diagnostics, p)
+		}
+		if m.ID != nil {
+			if m.Error != nil {
+				t.Fatalf("request %d failed: %v", *m.ID, m.Error)
+			}
+			results[*m.ID] = m.Result
+		}
+	}
+	if !strings.Contains(string(results[1]), `"documentFormattingProvider":true`) {
+		t.Errorf("expected formatting capability, got %s", results[1])
+	}
+	if len(diagnostics) != 2 || len(diagnostics[0].Diagnostics) != 0 {
+		t.Fatalf("expected clean open then one change, got %+v", diagnostics)
+	}
+	got := diagnostics[1].Diagnostics
+	if len(got) != 1 || got[0].Code != "TL010" || got[0].Range != diag.Range || diagnostics[1].Version != 2 {
+		t.Errorf("expected TL010 diagnostic on the trailing spaces, got %+v", diagnostics[1])
+	}
+	var actions []codeAction
+	if err := json.Unmarshal(results[2], &actions); err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[0].Title != "Remove trailing whitespace (TL010)" {
+		t.Fatalf("unexpected code actions %+v", actions)
+	}
+	if edits := actions[0].Edit.Changes[uri]; len(edits) != 1 || edits[0].Range != diag.Range || edits[0].NewText != "" {
+		t.Errorf("expected the quick fix to delete the trailing spaces only, got %+v", edits)
+	}
+	if edits := actions[1].Edit.Changes[uri]; len(edits) != 1 || edits[0].Range != diag.Range || edits[0].NewText != "" {
+		t.Errorf("expected fix all to send only the changed span, got %+v", edits)
+	}
+	var edits []TextEdit
+	if err := json.Unmarshal(results[3], &edits); err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].NewText != "hello\nworld\n" || edits[0].Range.End != (Position{2, 0}) {
+		t.Errorf("unexpected formatting edits %+v", edits)
+	}
+	if string(results[4]) != "null" {
+		t.Errorf("expected null shutdown result, got %s", results[4])
+	}
+}
+
+func TestServer_RespectsConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".prosefmt.json"), []byte(`{"rules": {"TL010": {"severity": "info"}, "TL001": {"enabled": false}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "a.md"))
+	s := &session{}
+	s.notify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "version": 1, "text": "x  "}})
+	s.request("textDocument/hover", map[string]any{})
+	msgs := run(t, s, "")
+	if len(msgs) != 2 {
+		t.Fatalf("expected diagnostics and an error response, got %+v", msgs)
+	}
+	var p publishDiagnosticsParams
+	json.Unmarshal(msgs[0].Params, &p)
+	if len(p.Diagnostics) != 1 || p.Diagnostics[0].Severity != severityInformation {
+		t.Errorf("expected one info TL010 diagnostic, got %+v", p.Diagnostics)
+	}
+	if msgs[1].Error == nil || msgs[1].Error.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %+v", msgs[1])
+	}
+}
+
+func TestServer_CodeActionsRespectSkip(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".prosefmt.json"), []byte(`{"skip": ["TL010"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "a.md"))
+	s := &session{}
+	s.notify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "version": 1, "text": "x  \ny"}})
+	diags := []Diagnostic{
+		{Range: Range{Start: Position{0, 1}, End: Position{0, 3}}, Code: "TL010", Source: source},
+		{Range: Range{Start: Position{1, 1}, End: Position{1, 1}}, Code: "TL001", Source: source},
+	}
+	s.request("textDocument/codeAction", map[string]any{"textDocument": map[string]any{"uri": uri}, "context": map[string]any{"diagnostics": diags}})
+	msgs := run(t, s, "")
+	var actions []codeAction
+	if err := json.Unmarshal(msgs[len(msgs)-1].Result, &actions); err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[0].Title != "Fix final newline (TL001)" {
+		t.Fatalf("expected only the TL001 quick fix and fix all, got %+v", actions)
+	}
+	want := TextEdit{Range: Range{Start: Position{1, 1}, End: Position{1, 1}}, NewText: "\n"}
+	for _, a := range actions {
+		if edits := a.Edit.Changes[uri]; len(edits) != 1 || edits[0] != want {
+			t.Errorf("%s: expected only the final newline to be added, got %+v", a.Title, edits)
+		}
+	}
+}
+
+func TestServer_PluginsRunOnOpenAndSave(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho run >> runs.log\necho '{\"issues\": [{\"line\": 1, \"column\": 1, \"message\": \"house rule\"}]}'\n"
+	if err := os.WriteFile(filepath.Join(dir, "house.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"plugins": [{"id": "HOUSE001", "command": ["./house.sh"]}]}`
+	if err := os.WriteFile(filepath.Join(dir, ".prosefmt.json"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "a.md"))
+	session := func() *session {
+		s := &session{}
+		s.notify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "version": 1, "text": "a\n"}})
+		for v := 2; v <= 3; v++ {
+			s.notify("textDocument/didChange", map[string]any{
+				"textDocument":   map[string]any{"uri": uri, "version": v},
+				"contentChanges": []any{map[string]any{"text": strings.Repeat("a", v) + "\n"}},
+			})
+		}
+		s.notify("textDocument/didSave", map[string]any{"textDocument": map[string]any{"uri": uri}})
+		return s
+	}
+	houseDiagnostics := func(msgs []received) []int {
+		var counts []int
+		for _, m := range msgs {
+			var p publishDiagnosticsParams
+			json.Unmarshal(m.Params, &p)
+			n := 0
+			for _, d := range p.Diagnostics {
+				if d.Code == "HOUSE001" {
+					n++
+				}
+			}
+			counts = append(counts, n)
+		}
+		return counts
+	}
+
+	msgs := run(t, session(), "")
+	if got := houseDiagnostics(msgs); len(got) != 4 || got[0]+got[1]+got[2]+got[3] != 0 {
+		t.Errorf("expected no plugin diagnostics without AllowPlugins, got %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "runs.log")); err == nil {
+		t.Fatal("expected the plugin not to run without AllowPlugins")
+	}
+
+	srv := New("", "test")
+	srv.AllowPlugins = true
+	msgs = runServer(t, session(), srv)
+	if got := houseDiagnostics(msgs); len(got) != 4 || got[0] != 1 || got[1] != 1 || got[2] != 1 || got[3] != 1 {
+		t.Errorf("expected the plugin diagnostic in every publish, got %v", got)
+	}
+	runs, err := os.ReadFile(filepath.Join(dir, "runs.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(runs), "run"); n != 2 {
+		t.Errorf("expected the plugin to run on open and save only, ran %d times", n)
+	}
+}
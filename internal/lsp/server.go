@@ -0,0 +1,453 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"prosefmt/internal/config"
+	"prosefmt/internal/custom"
+	"prosefmt/internal/log"
+	"prosefmt/internal/plugin"
+	"prosefmt/internal/rules"
+	"prosefmt/internal/scanner"
+	"unicode/utf8"
+)
+
+const source = "prosefmt"
+
+var actionTitles = map[string]string{
+	rules.TL001ID: "Fix final newline",
+	rules.TL010ID: "Remove trailing whitespace",
+	rules.TL011ID: "Replace non-ASCII characters",
+	rules.TL012ID: "Remove control characters",
+	rules.TL020ID: "Normalize Unicode",
+}
+
+type Server struct {
+	// AllowPlugins runs the plugin commands of the config. They run when a
+	// document is opened or saved, not on every change.
+	AllowPlugins bool
+	configFile   string
+	version      string
+	docs         map[string]*document
+	configs      map[string]*project
+	out          io.Writer
+	shutdown     bool
+}
+
+type project struct {
+	cfg     *config.Config
+	plugins []rules.Rule
+	extra   []rules.Rule
+}
+
+type document struct {
+	path    string
+	text    []byte
+	version int
+	plugins []rules.Issue
+}
+
+func New(configFile, version string) *Server {
+	return &Server{
+		configFile: configFile,
+		version:    version,
+		docs:       make(map[string]*document),
+		configs:    make(map[string]*project),
+	}
+}
+
+func (s *Server) Run(in io.Reader, out io.Writer) error {
+	s.out = out
+	r := bufio.NewReader(in)
+	for {
+		body, err := readMessage(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.replyError(json.RawMessage("null"), &rpcError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(req)
+		if req.ID == nil {
+			if err != nil {
+				log.Logf(log.Verbose, "lsp: %s: %v\n", req.Method, err)
+			}
+			continue
+		}
+		if err != nil {
+			var rerr *rpcError
+			if !errors.As(err, &rerr) {
+				rerr = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+			}
+			err = s.replyError(req.ID, rerr)
+		} else {
+			err = writeMessage(s.out, response{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) replyError(id json.RawMessage, e *rpcError) error {
+	return writeMessage(s.out, errorResponse{JSONRPC: "2.0", ID: id, Error: e})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) handle(req request) (any, error) {
+	if s.shutdown && req.Method != "exit" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    syncIncremental,
+					"save":      true,
+				},
+				"codeActionProvider":         true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]any{"name": source, "version": s.version},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		doc := &document{path: uriToPath(p.TextDocument.URI), text: []byte(p.TextDocument.Text), version: p.TextDocument.Version}
+		s.docs[p.TextDocument.URI] = doc
+		s.runPlugins(doc)
+		return nil, s.publish(p.TextDocument.URI, doc)
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return nil, errors.New("unknown document " + p.TextDocument.URI)
+		}
+		for _, c := range p.ContentChanges {
+			doc.text = applyChange(doc.text, c)
+		}
+		doc.version = p.TextDocument.Version
+		return nil, s.publish(p.TextDocument.URI, doc)
+	case "textDocument/didSave":
+		var p didSaveParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if filepath.Base(uriToPath(p.TextDocument.URI)) != config.FileName {
+			doc, ok := s.docs[p.TextDocument.URI]
+			if !ok {
+				return nil, nil
+			}
+			s.runPlugins(doc)
+			return nil, s.publish(p.TextDocument.URI, doc)
+		}
+		s.configs = make(map[string]*project)
+		for uri, doc := range s.docs {
+			s.runPlugins(doc)
+			if err := s.publish(uri, doc); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "workspace/didChangeWatchedFiles":
+		s.configs = make(map[string]*project)
+		return nil, nil
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	case "textDocument/codeAction":
+		var p codeActionParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.codeActions(p)
+	case "textDocument/formatting":
+		var p formattingParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return nil, errors.New("unknown document " + p.TextDocument.URI)
+		}
+		opts := s.options(doc)
+		return fullEdit(doc.text, rules.FixWith(doc.text, opts)), nil
+	}
+	if req.ID == nil {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+func (s *Server) publish(uri string, doc *document) error {
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Version:     doc.version,
+		Diagnostics: s.diagnostics(doc),
+	})
+}
+
+func (s *Server) diagnostics(doc *document) []Diagnostic {
+	opts := s.options(doc)
+	issues := rules.CheckWith(doc.path, doc.text, opts)
+	if filepath.IsAbs(doc.path) {
+		issues = append(rules.CheckPaths([]string{doc.path}, func(string) rules.Options { return opts }), issues...)
+	}
+	issues = append(issues, doc.plugins...)
+	diags := []Diagnostic{}
+	for _, i := range issues {
+		diags = append(diags, Diagnostic{
+			Range:    issueRange(doc.text, i),
+			Severity: diagnosticSeverity(i.Severity),
+			Code:     i.RuleID,
+			Source:   source,
+			Message:  i.Message,
+		})
+	}
+	return diags
+}
+
+// runPlugins checks doc with the plugin rules only and keeps the issues
+// until the next run.
+func (s *Server) runPlugins(doc *document) {
+	doc.plugins = nil
+	p := s.config(filepath.Dir(doc.path))
+	if !s.AllowPlugins || len(p.plugins) == 0 {
+		return
+	}
+	opts := s.options(doc)
+	only := make(map[string]bool)
+	for _, r := range p.plugins {
+		if opts.Select.Allows(r.ID) {
+			only[r.ID] = true
+		}
+	}
+	opts.Extra = p.plugins
+	opts.Select = rules.Selection{Only: only}
+	doc.plugins = rules.CheckWith(doc.path, doc.text, opts)
+}
+
+func (s *Server) codeActions(p codeActionParams) ([]codeAction, error) {
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, errors.New("unknown document " + p.TextDocument.URI)
+	}
+	opts := s.options(doc)
+	changes := rules.Changes(doc.path, doc.text, opts)
+	actions := []codeAction{}
+	seen := make(map[string]bool)
+	var fixable []Diagnostic
+	for _, d := range p.Context.Diagnostics {
+		if d.Source != source || !opts.HasFixer(d.Code) || !opts.Select.Allows(d.Code) {
+			continue
+		}
+		fixable = append(fixable, d)
+		if seen[d.Code] {
+			continue
+		}
+		seen[d.Code] = true
+		edits := s.ruleEdits(doc, opts, changes, d.Code)
+		if len(edits) == 0 {
+			continue
+		}
+		title, ok := actionTitles[d.Code]
+		if !ok {
+			title = "Fix " + d.Code
+		}
+		actions = append(actions, codeAction{
+			Title:       title + " (" + d.Code + ")",
+			Kind:        "quickfix",
+			Diagnostics: []Diagnostic{d},
+			IsPreferred: true,
+			Edit:        workspaceEdit{Changes: map[string][]TextEdit{p.TextDocument.URI: edits}},
+		})
+	}
+	if len(fixable) > 0 {
+		if edits := spanEdit(doc.text, rules.FixWith(doc.text, opts)); len(edits) > 0 {
+			actions = append(actions, codeAction{
+				Title:       "Fix all prosefmt issues",
+				Kind:        "source.fixAll.prosefmt",
+				Diagnostics: fixable,
+				Edit:        workspaceEdit{Changes: map[string][]TextEdit{p.TextDocument.URI: edits}},
+			})
+		}
+	}
+	return actions, nil
+}
+
+// ruleEdits returns the edits of one rule's issues. When they overlap, the
+// rule's whole-file fix is sent as a single edit instead.
+func (s *Server) ruleEdits(doc *document, opts rules.Options, changes []rules.Change, id string) []TextEdit {
+	var edits []rules.TextEdit
+	for _, c := range changes {
+		if c.RuleID == id {
+			edits = append(edits, c.Edit)
+		}
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+	if _, err := rules.ApplyEdits(doc.text, edits); err != nil {
+		opts.Select = rules.Selection{Only: map[string]bool{id: true}, Skip: opts.Select.Skip}
+		return spanEdit(doc.text, rules.FixWith(doc.text, opts))
+	}
+	out := make([]TextEdit, 0, len(edits))
+	for _, e := range edits {
+		out = append(out, TextEdit{Range: Range{Start: positionAt(doc.text, e.Start), End: positionAt(doc.text, e.End)}, NewText: e.NewText})
+	}
+	return out
+}
+
+func (s *Server) options(doc *document) rules.Options {
+	head, tail := scanner.HeadTail(doc.text)
+	fileType := scanner.DetectType(doc.path, head, tail)
+	p := s.config(filepath.Dir(doc.path))
+	cfg := p.cfg
+	opts := rules.Options{File: doc.path, Root: filepath.Dir(doc.path), Type: fileType, Rules: cfg.For(doc.path, fileType), Extra: p.extra}
+	if cfg != nil {
+		opts.Root = cfg.Dir
+		sel, err := rules.NewSelection(cfg.Only, cfg.Skip, append(p.plugins, p.extra...)...)
+		if err != nil {
+			log.Logf(log.Verbose, "lsp: %v\n", err)
+		}
+		opts.Select = sel
+	}
+	return opts
+}
+
+func (s *Server) config(dir string) *project {
+	if p, ok := s.configs[dir]; ok {
+		return p
+	}
+	file := s.configFile
+	if file == "" {
+		found, err := config.Find(dir)
+		if err != nil {
+			log.Logf(log.Verbose, "lsp: %v\n", err)
+		}
+		file = found
+	}
+	p := &project{}
+	if file != "" {
+		loaded, err := config.Load(file)
+		if err != nil {
+			log.Logf(log.Verbose, "lsp: %v\n", err)
+		} else {
+			p.cfg = loaded
+		}
+		plugins := plugin.Rules(p.cfg)
+		customRules, err := custom.Rules(p.cfg)
+		if err == nil {
+			err = rules.ValidateExtra(append(plugins, customRules...))
+		}
+		if err != nil {
+			log.Logf(log.Verbose, "lsp: %v\n", err)
+		} else {
+			p.plugins, p.extra = plugins, customRules
+		}
+	}
+	s.configs[dir] = p
+	return p
+}
+
+func issueRange(text []byte, i rules.Issue) Range {
+	if i.Line == 0 {
+		return Range{}
+	}
+	start := min(max(lineOffset(text, i.Line)+i.Column-1, 0), len(text))
+	end := start
+	for end < len(text) && (text[end] == ' ' || text[end] == '\t') {
+		end++
+	}
+	if end == start && end < len(text) && text[end] != '\n' && text[end] != '\r' {
+		_, size := utf8.DecodeRune(text[end:])
+		end += size
+	}
+	if i.EndLine > i.Line {
+		end = lineContentEnd(text, lineOffset(text, i.EndLine))
+	}
+	return Range{Start: positionAt(text, start), End: positionAt(text, end)}
+}
+
+func lineOffset(text []byte, line int) int {
+	off := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(text[off:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		off += i + 1
+	}
+	return off
+}
+
+func diagnosticSeverity(s rules.Severity) int {
+	switch s {
+	case rules.SeverityInfo:
+		return severityInformation
+	case rules.SeverityWarning:
+		return severityWarning
+	}
+	return severityError
+}
+
+func spanEdit(text, fixed []byte) []TextEdit {
+	if bytes.Equal(text, fixed) {
+		return []TextEdit{}
+	}
+	p := 0
+	for p < len(text) && p < len(fixed) && text[p] == fixed[p] {
+		p++
+	}
+	for p > 0 && p < len(text) && (!utf8.RuneStart(text[p]) || text[p] == '\n' && text[p-1] == '\r') {
+		p--
+	}
+	s := 0
+	for s < len(text)-p && s < len(fixed)-p && text[len(text)-1-s] == fixed[len(fixed)-1-s] {
+		s++
+	}
+	for s > 0 && (!utf8.RuneStart(text[len(text)-s]) || text[len(text)-s] == '\n' && len(text)-s > 0 && text[len(text)-s-1] == '\r') {
+		s--
+	}
+	return []TextEdit{{Range: Range{Start: positionAt(text, p), End: positionAt(text, len(text)-s)}, NewText: string(fixed[p : len(fixed)-s])}}
+}
+
+func fullEdit(text, fixed []byte) []TextEdit {
+	if bytes.Equal(text, fixed) {
+		return []TextEdit{}
+	}
+	return []TextEdit{{Range: fullRange(text), NewText: string(fixed)}}
+}
@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+func offsetAt(text []byte, pos Position) int {
+	off := 0
+	for line := 0; line < pos.Line; line++ {
+		next := lineEnd(text, off)
+		if next == len(text) {
+			return len(text)
+		}
+		off = next
+	}
+	end := lineContentEnd(text, off)
+	for units := 0; units < pos.Character && off < end; {
+		r, size := utf8.DecodeRune(text[off:])
+		units += utf16Len(r)
+		if units > pos.Character {
+			break
+		}
+		off += size
+	}
+	return off
+}
+
+func positionAt(text []byte, off int) Position {
+	if off > len(text) {
+		off = len(text)
+	}
+	var pos Position
+	start := 0
+	for {
+		next := lineEnd(text, start)
+		if off < next || next == lineContentEnd(text, start) {
+			break
+		}
+		start = next
+		pos.Line++
+	}
+	for i := start; i < off; {
+		r, size := utf8.DecodeRune(text[i:])
+		pos.Character += utf16Len(r)
+		i += size
+	}
+	return pos
+}
+
+func lineEnd(text []byte, start int) int {
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			return i + 1
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				return i + 2
+			}
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+func lineContentEnd(text []byte, start int) int {
+	for i := start; i < len(text); i++ {
+		if text[i] == '\n' || text[i] == '\r' {
+			return i
+		}
+	}
+	return len(text)
+}
+
+func utf16Len(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1
+}
+
+func applyChange(text []byte, c contentChange) []byte {
+	if c.Range == nil {
+		return []byte(c.Text)
+	}
+	start, end := offsetAt(text, c.Range.Start), offsetAt(text, c.Range.End)
+	if end < start {
+		start, end = end, start
+	}
+	out := make([]byte, 0, len(text)-(end-start)+len(c.Text))
+	out = append(out, text[:start]...)
+	out = append(out, c.Text...)
+	return append(out, text[end:]...)
+}
+
+func fullRange(text []byte) Range {
+	return Range{End: positionAt(text, len(text))}
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	p := u.Path
+	if runtime.GOOS == "windows" {
+		p = strings.TrimPrefix(p, "/")
+	}
+	return filepath.FromSlash(p)
+}
@@ -0,0 +1,158 @@
+//go:build ignore
+
+// gen writes tables.go from the Unicode Character Database.
+//
+//	go run gen.go -version 14.0.0
+//	go run gen.go -version 14.0.0 -local /path/to/ucd
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	version = flag.String("version", "14.0.0", "Unicode version")
+	local   = flag.String("local", "", "directory with UnicodeData.txt and CompositionExclusions.txt (default: download)")
+	output  = flag.String("o", "tables.go", "output file")
+)
+
+func open(name string) io.ReadCloser {
+	if *local != "" {
+		f, err := os.Open(filepath.Join(*local, name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return f
+	}
+	url := fmt.Sprintf("https://www.unicode.org/Public/%s/ucd/%s", *version, name)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("%s: %s", url, resp.Status)
+	}
+	return resp.Body
+}
+
+func parseRune(s string) rune {
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return rune(n)
+}
+
+func main() {
+	flag.Parse()
+	ccc := make(map[rune]int)
+	single := make(map[rune][]rune)
+	r := open("UnicodeData.txt")
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Split(sc.Text(), ";")
+		if len(fields) < 6 {
+			continue
+		}
+		c := parseRune(fields[0])
+		if n, _ := strconv.Atoi(fields[3]); n != 0 {
+			ccc[c] = n
+		}
+		d := fields[5]
+		if d == "" || strings.HasPrefix(d, "<") {
+			continue
+		}
+		for _, p := range strings.Fields(d) {
+			single[c] = append(single[c], parseRune(p))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		log.Fatal(err)
+	}
+	r.Close()
+
+	excluded := make(map[rune]bool)
+	r = open("CompositionExclusions.txt")
+	sc = bufio.NewScanner(r)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			excluded[parseRune(line)] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		log.Fatal(err)
+	}
+	r.Close()
+
+	var full func(c rune) []rune
+	full = func(c rune) []rune {
+		d, ok := single[c]
+		if !ok {
+			return []rune{c}
+		}
+		var out []rune
+		for _, x := range d {
+			out = append(out, full(x)...)
+		}
+		return out
+	}
+
+	var keys []rune
+	for c := range single {
+		keys = append(keys, c)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gen.go from Unicode %s; DO NOT EDIT.\n\n", *version)
+	fmt.Fprintf(&buf, "package norm\n\n")
+	fmt.Fprintf(&buf, "const UnicodeVersion = %q\n\n", *version)
+
+	fmt.Fprintf(&buf, "var decompositions = map[rune]string{\n")
+	for _, c := range keys {
+		fmt.Fprintf(&buf, "0x%04X: %+q,\n", c, string(full(c)))
+	}
+	fmt.Fprintf(&buf, "}\n\n")
+
+	var marks []rune
+	for c := range ccc {
+		marks = append(marks, c)
+	}
+	sort.Slice(marks, func(i, j int) bool { return marks[i] < marks[j] })
+	fmt.Fprintf(&buf, "var combiningClass = map[rune]uint8{\n")
+	for _, c := range marks {
+		fmt.Fprintf(&buf, "0x%04X: %d,\n", c, ccc[c])
+	}
+	fmt.Fprintf(&buf, "}\n\n")
+
+	fmt.Fprintf(&buf, "var compositions = map[[2]rune]rune{\n")
+	for _, c := range keys {
+		d := single[c]
+		if len(d) != 2 || excluded[c] || ccc[c] != 0 || ccc[d[0]] != 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "{0x%04X, 0x%04X}: 0x%04X,\n", d[0], d[1], c)
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
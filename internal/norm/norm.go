@@ -0,0 +1,151 @@
+package norm
+
+//go:generate go run gen.go -version 14.0.0
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type Form int
+
+const (
+	NFC Form = iota
+	NFD
+)
+
+func (f Form) String() string {
+	if f == NFD {
+		return "NFD"
+	}
+	return "NFC"
+}
+
+func ParseForm(s string) (Form, error) {
+	switch strings.ToUpper(s) {
+	case "", "NFC":
+		return NFC, nil
+	case "NFD":
+		return NFD, nil
+	}
+	return NFC, fmt.Errorf("unknown normalization form %q (want NFC or NFD)", s)
+}
+
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+func (f Form) Bytes(b []byte) []byte {
+	if isASCII(b) || !utf8.Valid(b) {
+		return b
+	}
+	runes := decompose(b)
+	if f == NFC {
+		runes = compose(runes)
+	}
+	out := make([]byte, 0, len(b))
+	for _, r := range runes {
+		out = utf8.AppendRune(out, r)
+	}
+	return out
+}
+
+func (f Form) IsNormal(b []byte) bool {
+	return isASCII(b) || string(f.Bytes(b)) == string(b)
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func decompose(b []byte) []rune {
+	runes := make([]rune, 0, len(b))
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		if s := r - hangulSBase; s >= 0 && s < hangulSCount {
+			runes = append(runes, hangulLBase+s/hangulNCount, hangulVBase+(s%hangulNCount)/hangulTCount)
+			if t := s % hangulTCount; t != 0 {
+				runes = append(runes, hangulTBase+t)
+			}
+			continue
+		}
+		if d, ok := decompositions[r]; ok {
+			runes = append(runes, []rune(d)...)
+			continue
+		}
+		runes = append(runes, r)
+	}
+	reorder(runes)
+	return runes
+}
+
+func reorder(runes []rune) {
+	for i := 0; i < len(runes); {
+		if combiningClass[runes[i]] == 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && combiningClass[runes[j]] != 0 {
+			j++
+		}
+		run := runes[i:j]
+		sort.SliceStable(run, func(a, b int) bool { return combiningClass[run[a]] < combiningClass[run[b]] })
+		i = j
+	}
+}
+
+func compose(runes []rune) []rune {
+	if len(runes) == 0 {
+		return runes
+	}
+	out := runes[:0]
+	starter := -1
+	var lastClass uint8
+	for _, r := range runes {
+		class := combiningClass[r]
+		if starter >= 0 && (lastClass < class || lastClass == 0 && len(out)-1 == starter) {
+			if c, ok := composePair(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+	return out
+}
+
+func composePair(a, b rune) (rune, bool) {
+	if l := a - hangulLBase; l >= 0 && l < hangulLCount {
+		if v := b - hangulVBase; v >= 0 && v < hangulVCount {
+			return hangulSBase + (l*hangulVCount+v)*hangulTCount, true
+		}
+	}
+	if s := a - hangulSBase; s >= 0 && s < hangulSCount && s%hangulTCount == 0 {
+		if t := b - hangulTBase; t > 0 && t < hangulTCount {
+			return a + t, true
+		}
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
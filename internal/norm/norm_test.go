@@ -0,0 +1,58 @@
+package norm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		in, nfc, nfd string
+	}{
+		{"plain ascii", "plain ascii", "plain ascii"},
+		{"e\u0301te\u0301", "\u00e9t\u00e9", "e\u0301te\u0301"},
+		{"\u1e9b\u0323", "\u1e9b\u0323", "\u017f\u0323\u0307"},
+		{"a\u0301\u0328", "\u0105\u0301", "a\u0328\u0301"},
+		{"\u212b", "\u00c5", "A\u030a"},
+		{"\u1100\u1161\u11a8", "\uac01", "\u1100\u1161\u11a8"},
+		{"\uac01", "\uac01", "\u1100\u1161\u11a8"},
+		{"\u0958", "\u0915\u093c", "\u0915\u093c"},
+	}
+	for _, tt := range tests {
+		if got := string(NFC.Bytes([]byte(tt.in))); got != tt.nfc {
+			t.Errorf("NFC(%+q) = %+q, want %+q", tt.in, got, tt.nfc)
+		}
+		if got := string(NFD.Bytes([]byte(tt.in))); got != tt.nfd {
+			t.Errorf("NFD(%+q) = %+q, want %+q", tt.in, got, tt.nfd)
+		}
+	}
+}
+
+func TestIsNormal(t *testing.T) {
+	if !NFC.IsNormal([]byte("caf\u00e9")) || NFC.IsNormal([]byte("cafe\u0301")) {
+		t.Error("NFC.IsNormal mismatch")
+	}
+	if NFD.IsNormal([]byte("caf\u00e9")) || !NFD.IsNormal([]byte("cafe\u0301")) {
+		t.Error("NFD.IsNormal mismatch")
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	if f, err := ParseForm("nfd"); err != nil || f != NFD {
+		t.Errorf("expected NFD, got %v %v", f, err)
+	}
+	if f, err := ParseForm(""); err != nil || f != NFC {
+		t.Errorf("expected NFC default, got %v %v", f, err)
+	}
+	if _, err := ParseForm("NFKC"); err == nil {
+		t.Error("expected error for unsupported form")
+	}
+}
+
+func TestBytesLongCombiningRun(t *testing.T) {
+	in := "a" + strings.Repeat("\u0301\u0323", 40000)
+	want := "a" + strings.Repeat("\u0323", 40000) + strings.Repeat("\u0301", 40000)
+	if got := string(NFD.Bytes([]byte(in))); got != want {
+		t.Error("NFD did not reorder a long run of combining marks")
+	}
+}
@@ -0,0 +1,196 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"prosefmt/internal/config"
+	"prosefmt/internal/rules"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProtocolVersion = 1
+	defaultTimeout  = 30 * time.Second
+)
+
+type Request struct {
+	Version int    `json:"version"`
+	ID      string `json:"id"`
+	File    string `json:"file"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+type Response struct {
+	Issues []Issue `json:"issues"`
+	Edits  []Edit  `json:"edits,omitempty"`
+}
+
+type Issue struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	EndLine int    `json:"endLine,omitempty"`
+	Message string `json:"message"`
+}
+
+type Edit struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	NewText string `json:"newText"`
+}
+
+type plugin struct {
+	config.Plugin
+	cfg     *config.Config
+	timeout time.Duration
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]result
+}
+
+type result struct {
+	resp Response
+	err  error
+}
+
+// Rules returns a rule for each plugin in cfg.
+func Rules(cfg *config.Config) []rules.Rule {
+	if cfg == nil {
+		return nil
+	}
+	var out []rules.Rule
+	for _, p := range cfg.Plugins {
+		pl := &plugin{Plugin: p, cfg: cfg, timeout: defaultTimeout, cache: make(map[[sha256.Size]byte]result)}
+		if p.Timeout != "" {
+			pl.timeout, _ = time.ParseDuration(p.Timeout)
+		}
+		description := p.Description
+		if description == "" {
+			description = "Plugin rule (" + strings.Join(p.Command, " ") + ")."
+		}
+		out = append(out, rules.Rule{
+			ID:             strings.ToUpper(p.ID),
+			Description:    description,
+			DefaultEnabled: true,
+			Check:          pl.check,
+			Fix:            pl.fix,
+			PartialFix:     true,
+		})
+	}
+	return out
+}
+
+func (p *plugin) applies(file string, opts rules.Options) bool {
+	if len(p.Files) == 0 && len(p.Types) == 0 {
+		return true
+	}
+	return p.cfg.Matches(p.Files, p.Types, file, opts.Type)
+}
+
+func (p *plugin) check(file string, content []byte, opts rules.Options) []rules.Issue {
+	if !p.applies(file, opts) {
+		return nil
+	}
+	id := strings.ToUpper(p.ID)
+	resp, err := p.run(file, content, opts)
+	if err != nil {
+		return []rules.Issue{{File: file, RuleID: id, Message: "plugin failed: " + err.Error()}}
+	}
+	issues := make([]rules.Issue, 0, len(resp.Issues))
+	for _, i := range resp.Issues {
+		line, column, endLine := position(i)
+		issues = append(issues, rules.Issue{File: file, Line: line, Column: column, EndLine: endLine, RuleID: id, Message: i.Message})
+	}
+	return issues
+}
+
+// position clamps the position a plugin reports: a line below 1 means the
+// whole file, and columns start at 1.
+func position(i Issue) (line, column, endLine int) {
+	if i.Line < 1 {
+		return 0, 0, 0
+	}
+	if i.EndLine > i.Line {
+		endLine = i.EndLine
+	}
+	return i.Line, max(i.Column, 1), endLine
+}
+
+func (p *plugin) fix(content []byte, opts rules.Options) []byte {
+	if !p.applies(opts.File, opts) {
+		return content
+	}
+	resp, err := p.run(opts.File, content, opts)
+	if err != nil || len(resp.Edits) == 0 {
+		return content
+	}
+	out, err := applyEdits(content, resp.Edits)
+	if err != nil {
+		return content
+	}
+	return out
+}
+
+func (p *plugin) run(file string, content []byte, opts rules.Options) (Response, error) {
+	key := sha256.Sum256(append([]byte(file+"\x00"), content...))
+	p.mu.Lock()
+	r, ok := p.cache[key]
+	p.mu.Unlock()
+	if ok {
+		return r.resp, r.err
+	}
+	resp, err := p.invoke(Request{Version: ProtocolVersion, ID: strings.ToUpper(p.ID), File: file, Type: opts.Type, Content: string(content)})
+	p.mu.Lock()
+	p.cache[key] = result{resp: resp, err: err}
+	p.mu.Unlock()
+	return resp, err
+}
+
+func (p *plugin) invoke(req Request) (Response, error) {
+	var resp Response
+	in, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	name := p.Command[0]
+	if strings.ContainsAny(name, `/\`) && !filepath.IsAbs(name) {
+		name = filepath.Join(p.cfg.Dir, name)
+	}
+	cmd := exec.CommandContext(ctx, name, p.Command[1:]...)
+	cmd.Dir = p.cfg.Dir
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return resp, fmt.Errorf("timed out after %s", p.timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return resp, fmt.Errorf("%w: %s", err, msg)
+		}
+		return resp, err
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return resp, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp, nil
+}
+
+func applyEdits(content []byte, edits []Edit) ([]byte, error) {
+	converted := make([]rules.TextEdit, len(edits))
+	for i, e := range edits {
+		converted[i] = rules.TextEdit{Start: e.Start, End: e.End, NewText: e.NewText}
+	}
+	return rules.ApplyEdits(content, converted)
+}
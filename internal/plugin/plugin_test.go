@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"prosefmt/internal/config"
+	"prosefmt/internal/rules"
+	"strings"
+	"testing"
+)
+
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("PROSEFMT_PLUGIN_HELPER") != "1" {
+		return
+	}
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		os.Exit(3)
+	}
+	if strings.Contains(req.Content, "CRASH") {
+		os.Stderr.WriteString("boom")
+		os.Exit(1)
+	}
+	resp := Response{Issues: []Issue{}}
+	for n, line := range strings.Split(req.Content, "\n") {
+		if i := strings.Index(line, "Prosefmt"); i >= 0 {
+			resp.Issues = append(resp.Issues, Issue{Line: n + 1, Column: i + 1, Message: "write ProseFmt"})
+		}
+	}
+	for off := 0; ; {
+		i := strings.Index(req.Content[off:], "Prosefmt")
+		if i < 0 {
+			break
+		}
+		resp.Edits = append(resp.Edits, Edit{Start: off + i, End: off + i + len("Prosefmt"), NewText: "ProseFmt"})
+		off += i + len("Prosefmt")
+	}
+	json.NewEncoder(os.Stdout).Encode(resp)
+	os.Exit(0)
+}
+
+func helperConfig(t *testing.T, id string, files ...string) *config.Config {
+	t.Setenv("PROSEFMT_PLUGIN_HELPER", "1")
+	return &config.Config{
+		Dir: t.TempDir(),
+		Plugins: []config.Plugin{{
+			ID:      id,
+			Command: []string{os.Args[0], "-test.run=^TestHelperPlugin$"},
+			Files:   files,
+		}},
+	}
+}
+
+func TestPlugin_CheckAndFix(t *testing.T) {
+	cfg := helperConfig(t, "house001")
+	extra := Rules(cfg)
+	if err := rules.ValidateExtra(extra); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(cfg.Dir, "a.md")
+	content := []byte("Use Prosefmt  \nand Prosefmt\n")
+	opts := rules.Options{File: file, Rules: config.RuleSet{"HOUSE001": {Severity: "warning"}}, Extra: extra}
+	var got []rules.Issue
+	for _, i := range rules.CheckWith(file, content, opts) {
+		if i.RuleID == "HOUSE001" {
+			got = append(got, i)
+		}
+	}
+	if len(got) != 2 || got[0].Line != 1 || got[0].Column != 5 || got[1].Line != 2 || got[0].Severity != rules.SeverityWarning {
+		t.Errorf("unexpected plugin issues %v", got)
+	}
+	if out := rules.FixWith(content, opts); string(out) != "Use ProseFmt\nand ProseFmt\n" {
+		t.Errorf("unexpected fix %q", out)
+	}
+	off := opts
+	off.Select, _ = rules.NewSelection(nil, []string{"HOUSE001"}, extra...)
+	for _, i := range rules.CheckWith(file, content, off) {
+		if i.RuleID == "HOUSE001" {
+			t.Errorf("skipped plugin still reported %v", i)
+		}
+	}
+}
+
+func TestPlugin_FilesAndFailures(t *testing.T) {
+	cfg := helperConfig(t, "HOUSE002", "*.md")
+	extra := Rules(cfg)
+	txt := filepath.Join(cfg.Dir, "a.txt")
+	for _, i := range rules.CheckWith(txt, []byte("Prosefmt\n"), rules.Options{File: txt, Extra: extra}) {
+		if i.RuleID == "HOUSE002" {
+			t.Errorf("plugin ran outside its files: %v", i)
+		}
+	}
+	md := filepath.Join(cfg.Dir, "a.md")
+	var failed bool
+	for _, i := range rules.CheckWith(md, []byte("CRASH\n"), rules.Options{File: md, Extra: extra}) {
+		if i.RuleID == "HOUSE002" && strings.Contains(i.Message, "plugin failed") && strings.Contains(i.Message, "boom") {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Error("expected plugin failure to be reported")
+	}
+	if out := rules.FixWith([]byte("CRASH\n"), rules.Options{File: md, Extra: extra}); !bytes.Equal(out, []byte("CRASH\n")) {
+		t.Errorf("failing plugin must not change content, got %q", out)
+	}
+}
+
+func TestRules_ScopedToConfig(t *testing.T) {
+	a := helperConfig(t, "HOUSE003", "*.md")
+	b := helperConfig(t, "HOUSE003", "*.txt")
+	md := filepath.Join(a.Dir, "a.md")
+	count := func(extra []rules.Rule) int {
+		n := 0
+		for _, i := range rules.CheckWith(md, []byte("Prosefmt\n"), rules.Options{File: md, Extra: extra}) {
+			if i.RuleID == "HOUSE003" {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(Rules(a)); n != 1 {
+		t.Errorf("expected the first config's plugin to run, got %d issue(s)", n)
+	}
+	if n := count(Rules(b)); n != 0 {
+		t.Errorf("expected the second config's plugin to skip .md files, got %d issue(s)", n)
+	}
+	if n := count(nil); n != 0 {
+		t.Errorf("expected no plugin without its config, got %d issue(s)", n)
+	}
+	if err := rules.ValidateExtra(append(Rules(a), Rules(b)...)); err == nil {
+		t.Error("expected duplicate plugin IDs to be rejected")
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	out, err := applyEdits([]byte("abcdef"), []Edit{{Start: 4, End: 6, NewText: "X"}, {Start: 0, End: 1, NewText: ""}})
+	if err != nil || string(out) != "bcdX" {
+		t.Errorf("got %q, %v", out, err)
+	}
+	if _, err := applyEdits([]byte("abc"), []Edit{{Start: 0, End: 2}, {Start: 1, End: 3}}); err == nil {
+		t.Error("expected overlap error")
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		in                    Issue
+		line, column, endLine int
+	}{
+		{Issue{Line: 2, Column: 3, EndLine: 4}, 2, 3, 4},
+		{Issue{Line: 2}, 2, 1, 0},
+		{Issue{Line: 2, Column: -5, EndLine: 1}, 2, 1, 0},
+		{Issue{Line: -1, Column: 4, EndLine: 3}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		line, column, endLine := position(tt.in)
+		if line != tt.line || column != tt.column || endLine != tt.endLine {
+			t.Errorf("position(%+v) = %d:%d-%d, want %d:%d-%d", tt.in, line, column, endLine, tt.line, tt.column, tt.endLine)
+		}
+	}
+}
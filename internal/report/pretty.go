@@ -0,0 +1,184 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"prosefmt/internal/rules"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	bold   = "\x1b[1m"
+	dim    = "\x1b[2m"
+	red    = "\x1b[31m"
+	yellow = "\x1b[33m"
+	blue   = "\x1b[34m"
+	reset  = "\x1b[0m"
+)
+
+type pretty struct {
+	w       io.Writer
+	sources map[string][]byte
+	color   bool
+	err     error
+}
+
+func WritePretty(w io.Writer, issues []rules.Issue, filesScanned int, sources map[string][]byte, color bool) error {
+	sort.Slice(issues, func(a, b int) bool {
+		if issues[a].File != issues[b].File {
+			return issues[a].File < issues[b].File
+		}
+		if issues[a].Line != issues[b].Line {
+			return issues[a].Line < issues[b].Line
+		}
+		if issues[a].Column != issues[b].Column {
+			return issues[a].Column < issues[b].Column
+		}
+		return issues[a].RuleID < issues[b].RuleID
+	})
+	p := &pretty{w: w, sources: sources, color: color}
+	for start := 0; start < len(issues); {
+		end := start
+		for end < len(issues) && issues[end].File == issues[start].File {
+			end++
+		}
+		p.file(issues[start:end])
+		start = end
+	}
+	p.table(issues)
+	if filesScanned >= 0 {
+		p.printf("%d file(s) scanned, %d issue(s).\n", filesScanned, len(issues))
+	} else {
+		p.printf("%d file(s), %d issue(s).\n", len(fileSet(issues)), len(issues))
+	}
+	return p.err
+}
+
+type located struct {
+	rules.Issue
+	line, from, to int
+	note           string
+}
+
+func (p *pretty) file(issues []rules.Issue) {
+	content := p.sources[issues[0].File]
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	gutter := len(strconv.Itoa(len(lines)))
+	all := make([]located, len(issues))
+	for k, i := range issues {
+		all[k] = locate(content, lines, i)
+	}
+	sort.SliceStable(all, func(a, b int) bool {
+		if all[a].line != all[b].line {
+			return all[a].line < all[b].line
+		}
+		return all[a].from < all[b].from
+	})
+	p.printf("%s\n", p.paint(bold, issues[0].File))
+	for _, l := range all {
+		loc := "-"
+		if l.line > 0 {
+			loc = fmt.Sprintf("%d:%d", l.line, l.from+1)
+		}
+		p.printf("  %-8s %s  %s  %s\n", loc, p.paint(severityColor(l.Severity), fmt.Sprintf("%-7s", l.Severity)), p.paint(dim, l.RuleID), l.Message)
+		if l.line > 0 && l.line <= len(lines) && len(content) > 0 {
+			p.snippet(lines[l.line-1], gutter, l)
+		}
+	}
+	p.printf("\n")
+}
+
+func locate(content []byte, lines [][]byte, i rules.Issue) located {
+	l := located{Issue: i, line: i.Line, from: i.Column - 1, to: i.Column}
+	e := i.Edit
+	if e == nil || e.End > len(content) {
+		return l
+	}
+	l.line, l.from = position(lines, e.Start)
+	l.to = l.from + e.End - e.Start
+	removed := string(content[e.Start:e.End])
+	switch {
+	case e.Start == len(content) && strings.Trim(e.NewText, "\r\n") == "":
+		l.note = "no newline at end of file"
+	case removed != "" && strings.Trim(removed, "\r\n") == "" && e.NewText == "":
+		l.note = fmt.Sprintf("%d extra line break(s)", strings.Count(removed, "\n"))
+	}
+	return l
+}
+
+func (p *pretty) snippet(raw []byte, gutter int, l located) {
+	text := bytes.TrimRight(raw, "\r\n")
+	to := l.to
+	if to > len(text) && l.from < len(text) {
+		to = len(text)
+	}
+	marks := underline(text, l.from, to)
+	if l.note != "" {
+		marks += " " + l.note
+	}
+	p.printf("  %s\n", strings.TrimRight(p.paint(dim, fmt.Sprintf("%*d |", gutter+2, l.line))+" "+Visible(text), " "))
+	p.printf("  %s %s\n", p.paint(dim, fmt.Sprintf("%*s |", gutter+2, "")), p.paint(severityColor(l.Severity), marks))
+}
+
+func (p *pretty) table(issues []rules.Issue) {
+	if len(issues) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	files := make(map[string]map[string]bool)
+	var ids []string
+	for _, i := range issues {
+		if files[i.RuleID] == nil {
+			files[i.RuleID] = make(map[string]bool)
+			ids = append(ids, i.RuleID)
+		}
+		counts[i.RuleID]++
+		files[i.RuleID][i.File] = true
+	}
+	sort.Strings(ids)
+	width := len("Rule")
+	for _, id := range ids {
+		width = max(width, len(id))
+	}
+	p.printf("%s\n", p.paint(bold, fmt.Sprintf("%-*s  %6s  %5s", width, "Rule", "Issues", "Files")))
+	for _, id := range ids {
+		p.printf("%-*s  %6d  %5d\n", width, id, counts[id], len(files[id]))
+	}
+	p.printf("\n")
+}
+
+func (p *pretty) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+func (p *pretty) paint(color, text string) string {
+	if !p.color {
+		return text
+	}
+	return color + text + reset
+}
+
+func severityColor(s rules.Severity) string {
+	switch s {
+	case rules.SeverityInfo:
+		return blue
+	case rules.SeverityWarning:
+		return yellow
+	}
+	return red
+}
+
+func position(lines [][]byte, off int) (line, col int) {
+	for k, raw := range lines {
+		if off < len(raw) || k == len(lines)-1 {
+			return k + 1, off
+		}
+		off -= len(raw)
+	}
+	return 0, 0
+}
@@ -1,17 +1,27 @@
 package report
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"prosefmt/internal/rules"
 	"sort"
 )
 
 type Format string
 
-const FormatCompact Format = "compact"
+const (
+	FormatCompact Format = "compact"
+	FormatPretty  Format = "pretty"
+)
 
-func Write(w io.Writer, format Format, issues []rules.Issue, filesScanned int, files []string) error {
+// Write prints the report. sources maps each file to the content that was
+// checked; the pretty format takes its snippets from it.
+func Write(w io.Writer, format Format, issues []rules.Issue, filesScanned int, sources map[string][]byte, color bool) error {
+	if format == FormatPretty {
+		return WritePretty(w, issues, filesScanned, sources, color)
+	}
 	return writeCompact(w, issues, filesScanned)
 }
 
@@ -29,8 +39,7 @@ func writeCompact(w io.Writer, issues []rules.Issue, filesScanned int) error {
 		return issues[a].Column < issues[b].Column
 	})
 	for _, i := range issues {
-		_, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", i.File, i.Line, i.Column, i.RuleID, i.Message)
-		if err != nil {
+		if _, err := fmt.Fprintf(w, "%s: %s: %s: %s\n", location(i), i.Severity, i.RuleID, i.Message); err != nil {
 			return err
 		}
 	}
@@ -43,6 +52,42 @@ func writeCompact(w io.Writer, issues []rules.Issue, filesScanned int) error {
 	return err
 }
 
+type FileError struct {
+	File string
+	Err  error
+}
+
+func WriteErrors(w io.Writer, errs []FileError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Slice(errs, func(a, b int) bool { return errs[a].File < errs[b].File })
+	if _, err := fmt.Fprintf(w, "%d file(s) could not be processed:\n", len(errs)); err != nil {
+		return err
+	}
+	for _, e := range errs {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", e.File, errorMessage(e.Err)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func errorMessage(err error) string {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return pe.Op + ": " + pe.Err.Error()
+	}
+	return err.Error()
+}
+
+func location(i rules.Issue) string {
+	if i.Line == 0 {
+		return i.File
+	}
+	return fmt.Sprintf("%s:%d:%d", i.File, i.Line, i.Column)
+}
+
 func fileSet(issues []rules.Issue) map[string]bool {
 	m := make(map[string]bool)
 	for _, i := range issues {
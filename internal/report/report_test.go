@@ -2,6 +2,10 @@ package report
 
 import (
 	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"prosefmt/internal/rules"
 	"strings"
 	"testing"
@@ -13,11 +17,11 @@ func TestWrite_Compact(t *testing.T) {
 		{File: "a.txt", Line: 2, Column: 1, RuleID: "TL001", Message: "file must end with exactly one newline"},
 	}
 	var buf bytes.Buffer
-	if err := Write(&buf, FormatCompact, issues, 10, nil); err != nil {
+	if err := Write(&buf, FormatCompact, issues, 10, nil, false); err != nil {
 		t.Fatal(err)
 	}
 	out := buf.String()
-	if !strings.Contains(out, "a.txt:1:5: TL010: no trailing spaces") {
+	if !strings.Contains(out, "a.txt:1:5: error: TL010: no trailing spaces") {
 		t.Errorf("expected compact line for first issue, got %q", out)
 	}
 	if !strings.Contains(out, "10 file(s) scanned, 2 issue(s).") {
@@ -31,10 +35,107 @@ func TestWrite_Compact_TwoFiles(t *testing.T) {
 		{File: "b.txt", Line: 1, Column: 1, RuleID: "TL001", Message: "y"},
 	}
 	var buf bytes.Buffer
-	if err := Write(&buf, FormatCompact, issues, 6, nil); err != nil {
+	if err := Write(&buf, FormatCompact, issues, 6, nil, false); err != nil {
 		t.Fatal(err)
 	}
 	if !strings.Contains(buf.String(), "6 file(s) scanned, 2 issue(s).") {
 		t.Errorf("expected 6 file(s) scanned, 2 issue(s). in summary, got %q", buf.String())
 	}
 }
+
+func TestWrite_Compact_PathLevelIssue(t *testing.T) {
+	issues := []rules.Issue{
+		{File: "docs/aux.md", RuleID: "TL042", Message: "reserved"},
+		{File: "docs/aux.md", Line: 2, Column: 3, RuleID: "TL010", Message: "trailing"},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCompact, issues, 1, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "docs/aux.md: error: TL042: reserved\n") {
+		t.Errorf("expected path-level issue without line/column, got %q", out)
+	}
+	if !strings.Contains(out, "docs/aux.md:2:3: error: TL010: trailing\n") {
+		t.Errorf("expected line issue with line/column, got %q", out)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	errs := []FileError{
+		{File: "b.txt", Err: &fs.PathError{Op: "open", Path: "b.txt", Err: fs.ErrPermission}},
+		{File: "a.txt", Err: errors.New("rename failed")},
+	}
+	var buf bytes.Buffer
+	if err := WriteErrors(&buf, errs); err != nil {
+		t.Fatal(err)
+	}
+	want := "2 file(s) could not be processed:\na.txt: rename failed\nb.txt: open: permission denied\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestVisible(t *testing.T) {
+	got := Visible([]byte("a\tb \u00a0c\x00\xff \t"))
+	want := "a\tb <U+00A0>c^@\\xff\u00b7\u2192"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWritePretty(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	content := []byte("hello  \n\tx\n\nend")
+	if err := os.WriteFile(file, []byte("changed on disk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	issues := append(rules.CheckWith(file, content, rules.Options{}),
+		rules.Issue{File: file, Line: 2, Column: 2, RuleID: "X001", Severity: rules.SeverityWarning, Message: "no x"},
+		rules.Issue{File: filepath.Join(dir, "b.txt"), RuleID: "TL042", Message: "reserved"},
+	)
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatPretty, issues, 3, map[string][]byte{file: content}, false); err != nil {
+		t.Fatal(err)
+	}
+	want := file + "\n" +
+		"  1:6      error    TL010  no trailing spaces at end of line\n" +
+		"    1 | hello\u00b7\u00b7\n" +
+		"      |      ^^\n" +
+		"  2:2      warning  X001  no x\n" +
+		"    2 | \tx\n" +
+		"      | \t^\n" +
+		"  4:4      error    TL001  file must end with exactly one newline\n" +
+		"    4 | end\n" +
+		"      |    ^ no newline at end of file\n" +
+		"\n" +
+		filepath.Join(dir, "b.txt") + "\n" +
+		"  -        error    TL042  reserved\n" +
+		"\n" +
+		"Rule   Issues  Files\n" +
+		"TL001       1      1\n" +
+		"TL010       1      1\n" +
+		"TL042       1      1\n" +
+		"X001        1      1\n" +
+		"\n" +
+		"3 file(s) scanned, 4 issue(s).\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWritePretty_Color(t *testing.T) {
+	issues := []rules.Issue{{File: "missing.txt", Line: 1, Column: 1, RuleID: "TL010", Severity: rules.SeverityInfo, Message: "m"}}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatPretty, issues, -1, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "\x1b[1mmissing.txt\x1b[0m\n") || !strings.Contains(out, "\x1b[34minfo   \x1b[0m") {
+		t.Errorf("expected colored file and severity, got %q", out)
+	}
+	if !strings.HasSuffix(out, "1 file(s), 1 issue(s).\n") {
+		t.Errorf("unexpected summary in %q", out)
+	}
+}
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func Visible(line []byte) string {
+	var b strings.Builder
+	visible(line, func(_ int, piece string) { b.WriteString(piece) })
+	return b.String()
+}
+
+func visible(line []byte, emit func(off int, piece string)) {
+	trailing := len(bytes.TrimRight(line, " \t"))
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRune(line[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			emit(i, fmt.Sprintf("\\x%02x", line[i]))
+		case i >= trailing && r == ' ':
+			emit(i, "\u00b7")
+		case i >= trailing && r == '\t':
+			emit(i, "\u2192")
+		case r == '\t':
+			emit(i, "\t")
+		case r < 0x20:
+			emit(i, "^"+string(r+'@'))
+		case r == 0x7f:
+			emit(i, "^?")
+		case r > 0x7f && unicode.IsSpace(r):
+			emit(i, fmt.Sprintf("<U+%04X>", r))
+		default:
+			emit(i, string(line[i:i+size]))
+		}
+		i += size
+	}
+}
+
+func underline(line []byte, from, to int) string {
+	var pad, marks strings.Builder
+	visible(line, func(off int, piece string) {
+		switch {
+		case off < from && piece == "\t":
+			pad.WriteByte('\t')
+		case off < from:
+			pad.WriteString(strings.Repeat(" ", utf8.RuneCountInString(piece)))
+		case off < to:
+			marks.WriteString(strings.Repeat("^", utf8.RuneCountInString(piece)))
+		}
+	})
+	if marks.Len() == 0 {
+		marks.WriteByte('^')
+	}
+	return pad.String() + marks.String()
+}
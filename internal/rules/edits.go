@@ -0,0 +1,155 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+const MaxFixPasses = 10
+
+type TextEdit struct {
+	Start   int
+	End     int
+	NewText string
+}
+
+type OverlapError struct {
+	A, B TextEdit
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("edits %d-%d and %d-%d overlap", e.A.Start, e.A.End, e.B.Start, e.B.End)
+}
+
+func ApplyEdits(content []byte, edits []TextEdit) ([]byte, error) {
+	out, skipped, err := applyEdits(content, edits)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		return nil, &OverlapError{A: skipped[0][0], B: skipped[0][1]}
+	}
+	return out, nil
+}
+
+func applyEdits(content []byte, edits []TextEdit) ([]byte, [][2]TextEdit, error) {
+	sorted := append([]TextEdit(nil), edits...)
+	sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].Start < sorted[b].Start })
+	var out []byte
+	var skipped [][2]TextEdit
+	var last TextEdit
+	pos := 0
+	for _, e := range sorted {
+		if e.Start < 0 || e.End < e.Start || e.End > len(content) {
+			return nil, nil, fmt.Errorf("invalid edit %d-%d", e.Start, e.End)
+		}
+		if e.Start < pos {
+			skipped = append(skipped, [2]TextEdit{last, e})
+			continue
+		}
+		out = append(out, content[pos:e.Start]...)
+		out = append(out, e.NewText...)
+		pos = e.End
+		last = e
+	}
+	return append(out, content[pos:]...), skipped, nil
+}
+
+func issueEdits(issues []Issue) []TextEdit {
+	var edits []TextEdit
+	for _, i := range issues {
+		if i.Edit != nil {
+			edits = append(edits, *i.Edit)
+		}
+	}
+	return edits
+}
+
+func fixByEdits(content []byte, check func(content []byte) []Issue) []byte {
+	out := content
+	for pass := 0; pass < MaxFixPasses; pass++ {
+		edits := issueEdits(check(out))
+		if len(edits) == 0 {
+			break
+		}
+		next, _, err := applyEdits(out, edits)
+		if err != nil || string(next) == string(out) {
+			break
+		}
+		out = next
+	}
+	return out
+}
+
+type Change struct {
+	RuleID string
+	Edit   TextEdit
+	Issues []Issue
+}
+
+func Changes(file string, content []byte, opts Options) []Change {
+	if HasConflictMarkers(content) {
+		return nil
+	}
+	if opts.File == "" {
+		opts.File = file
+	}
+	fixed := make(map[string][]byte)
+	var changes []Change
+	type key struct {
+		id   string
+		edit TextEdit
+	}
+	index := make(map[key]int)
+	for _, i := range CheckWith(file, content, opts) {
+		r, ok := opts.lookup(i.RuleID)
+		if !ok || r.Fix == nil {
+			continue
+		}
+		var e TextEdit
+		if i.Edit != nil {
+			e = *i.Edit
+		} else {
+			out, ok := fixed[r.ID]
+			if !ok {
+				out = fixProtected(r, content, opts)
+				fixed[r.ID] = out
+			}
+			if e, ok = lineEdit(content, out, i.Line); !ok {
+				continue
+			}
+		}
+		if k, ok := index[key{r.ID, e}]; ok {
+			changes[k].Issues = append(changes[k].Issues, i)
+			continue
+		}
+		index[key{r.ID, e}] = len(changes)
+		changes = append(changes, Change{RuleID: r.ID, Edit: e, Issues: []Issue{i}})
+	}
+	sort.SliceStable(changes, func(a, b int) bool { return changes[a].Edit.Start < changes[b].Edit.Start })
+	return changes
+}
+
+func lineEdit(content, out []byte, line int) (TextEdit, bool) {
+	if bytes.Equal(content, out) {
+		return TextEdit{}, false
+	}
+	a, b := splitLines(content), splitLines(out)
+	if len(a) == len(b) && line > 0 && line <= len(a) && !bytes.Equal(a[line-1], b[line-1]) {
+		off := 0
+		for _, raw := range a[:line-1] {
+			off += len(raw)
+		}
+		return TextEdit{Start: off, End: off + len(a[line-1]), NewText: string(b[line-1])}, true
+	}
+	p := 0
+	for p < len(content) && p < len(out) && content[p] == out[p] {
+		p++
+	}
+	s := 0
+	for s < len(content)-p && s < len(out)-p && content[len(content)-1-s] == out[len(out)-1-s] {
+		s++
+	}
+	return TextEdit{Start: p, End: len(content) - s, NewText: string(out[p : len(out)-s])}, true
+}
@@ -0,0 +1,231 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"prosefmt/internal/norm"
+	"strings"
+)
+
+const (
+	TL040ID = "TL040"
+	TL041ID = "TL041"
+	TL042ID = "TL042"
+	TL043ID = "TL043"
+	TL044ID = "TL044"
+	TL045ID = "TL045"
+
+	defaultMaxPathLength = 260
+)
+
+type PathRule struct {
+	ID             string
+	Description    string
+	DefaultEnabled bool
+	Name           func(name string, siblings []string) string
+	Path           func(rel string, opts Options) string
+}
+
+var pathRegistry = []PathRule{
+	{
+		ID:             TL040ID,
+		Description:    "Path components must not end with a space or a dot.",
+		DefaultEnabled: true,
+		Name:           checkTL040,
+	},
+	{
+		ID:             TL041ID,
+		Description:    "Path components must not contain characters invalid on Windows.",
+		DefaultEnabled: true,
+		Name:           checkTL041,
+	},
+	{
+		ID:             TL042ID,
+		Description:    "Path components must not be reserved Windows device names.",
+		DefaultEnabled: true,
+		Name:           checkTL042,
+	},
+	{
+		ID:             TL043ID,
+		Description:    "File names must not collide on case-insensitive file systems.",
+		DefaultEnabled: true,
+		Name:           checkTL043,
+	},
+	{
+		ID:          TL044ID,
+		Description: "Path components must be in Unicode NFC.",
+		Name:        checkTL044,
+	},
+	{
+		ID:             TL045ID,
+		Description:    "Paths must not exceed the maximum length (260 by default).",
+		DefaultEnabled: true,
+		Path:           checkTL045,
+	},
+}
+
+func PathRules() []PathRule {
+	return append([]PathRule(nil), pathRegistry...)
+}
+
+// CheckPaths runs the path rules on each file. Names are checked below
+// opts.Root only, and an issue with a directory name is reported once, on
+// the first file under that directory.
+func CheckPaths(paths []string, optionsFor func(path string) Options) []Issue {
+	var issues []Issue
+	dirs := make(map[string][]string)
+	siblingsOf := func(dir string) []string {
+		names, ok := dirs[dir]
+		if !ok {
+			names = readDirNames(dir)
+			dirs[dir] = names
+		}
+		return names
+	}
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		opts := optionsFor(p)
+		rel := relPath(opts.Root, p)
+		parts := pathComponents(rel)
+		full := make([]string, len(parts))
+		for k, cur := len(parts)-1, filepath.Clean(p); k >= 0; k, cur = k-1, filepath.Dir(cur) {
+			full[k] = cur
+		}
+		for k, name := range parts {
+			dir := k < len(parts)-1
+			if dir {
+				if seen[full[k]] {
+					continue
+				}
+				seen[full[k]] = true
+			}
+			for _, r := range pathRegistry {
+				if r.Name == nil || !opts.ruleEnabled(r.ID, r.DefaultEnabled) {
+					continue
+				}
+				msg := r.Name(name, siblingsOf(filepath.Dir(full[k])))
+				if msg == "" {
+					continue
+				}
+				if dir {
+					msg = "directory " + msg
+				}
+				issues = append(issues, pathIssue(p, r.ID, msg, opts))
+			}
+		}
+		for _, r := range pathRegistry {
+			if r.Path == nil || !opts.ruleEnabled(r.ID, r.DefaultEnabled) {
+				continue
+			}
+			if msg := r.Path(rel, opts); msg != "" {
+				issues = append(issues, pathIssue(p, r.ID, msg, opts))
+			}
+		}
+	}
+	return issues
+}
+
+func relPath(root, path string) string {
+	if root == "" {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+func readDirNames(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func pathComponents(path string) []string {
+	path = path[len(filepath.VolumeName(path)):]
+	var parts []string
+	for _, c := range strings.Split(filepath.ToSlash(path), "/") {
+		if c != "" && c != "." && c != ".." {
+			parts = append(parts, c)
+		}
+	}
+	return parts
+}
+
+func pathIssue(path, id, msg string, opts Options) Issue {
+	return Issue{File: path, RuleID: id, Message: msg, Severity: opts.severity(id, SeverityError)}
+}
+
+func checkTL040(name string, _ []string) string {
+	if strings.HasSuffix(name, " ") || strings.HasSuffix(name, ".") {
+		return fmt.Sprintf("name %q ends with a space or a dot", name)
+	}
+	return ""
+}
+
+func checkTL041(name string, _ []string) string {
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return r < 0x20 || strings.ContainsRune(`<>:"|?*\`, r)
+	}); i >= 0 {
+		return fmt.Sprintf("name %q contains %q, which is invalid on Windows", name, name[i])
+	}
+	return ""
+}
+
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+func checkTL042(name string, _ []string) string {
+	stem, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		return fmt.Sprintf("name %q is a reserved device name on Windows", name)
+	}
+	return ""
+}
+
+func foldName(name string) string {
+	return strings.ToLower(string(norm.NFC.Bytes([]byte(name))))
+}
+
+func checkTL043(name string, siblings []string) string {
+	folded := foldName(name)
+	var clashes []string
+	for _, s := range siblings {
+		if s != name && foldName(s) == folded {
+			clashes = append(clashes, fmt.Sprintf("%q", s))
+		}
+	}
+	if len(clashes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("name collides with %s on case-insensitive file systems", strings.Join(clashes, ", "))
+}
+
+func checkTL044(name string, _ []string) string {
+	if !norm.NFC.IsNormal([]byte(name)) {
+		return fmt.Sprintf("name %+q is not in Unicode NFC", name)
+	}
+	return ""
+}
+
+func checkTL045(rel string, opts Options) string {
+	limit := opts.Rules.Get(TL045ID).Max
+	if limit <= 0 {
+		limit = defaultMaxPathLength
+	}
+	if n := len([]rune(filepath.ToSlash(rel))); n > limit {
+		return fmt.Sprintf("path is %d characters long (max %d)", n, limit)
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package rules
+
+import "bytes"
+
+type tabProtection struct {
+	reason   string
+	protects func(line []byte, i int) bool
+}
+
+var protections = map[string]tabProtection{
+	"makefile": {"recipe tabs in Makefiles", leadingTab},
+	"go":       {"gofmt indentation tabs in Go files", leadingTab},
+	"tsv":      {"column tabs in TSV files", func([]byte, int) bool { return true }},
+}
+
+var placeholders = []byte{0x1a, 0x1c, 0x1d, 0x1e, 0x1f, 0x01, 0x02, 0x03}
+
+func leadingTab(line []byte, i int) bool {
+	for j := 0; j < i; j++ {
+		if line[j] != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+func protectionFor(file string, opts Options) (tabProtection, bool) {
+	p, ok := protections[opts.fileType(file)]
+	return p, ok
+}
+
+func ProtectionReason(file string, opts Options) string {
+	p, _ := protectionFor(file, opts)
+	return p.reason
+}
+
+type tabMask struct {
+	content []byte
+	// ph is the placeholder used for the whole file. When every placeholder
+	// byte occurs in the file, each line gets its own in lines.
+	ph      byte
+	lines   []byte
+	orig    [][]byte
+	skipped map[int]bool
+}
+
+func freePlaceholder(b []byte) byte {
+	for _, c := range placeholders {
+		if bytes.IndexByte(b, c) < 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// maskTabs replaces the protected tabs with a placeholder byte so the
+// whitespace rules leave them alone. Lines that contain every placeholder
+// byte are not masked; they are listed in skipped (by line number) and the
+// whitespace rules must not touch them.
+func maskTabs(content []byte, p tabProtection) tabMask {
+	m := tabMask{content: append([]byte(nil), content...), ph: freePlaceholder(content), skipped: make(map[int]bool)}
+	off := 0
+	for k, raw := range splitLines(content) {
+		line, _ := stripLineEnding(raw)
+		ph := m.ph
+		if ph == 0 {
+			ph = freePlaceholder(raw)
+		}
+		var used byte
+		for i, c := range line {
+			if c != '\t' || !p.protects(line, i) {
+				continue
+			}
+			if ph == 0 {
+				m.skipped[k+1] = true
+				break
+			}
+			m.content[off+i] = ph
+			used = ph
+		}
+		m.lines = append(m.lines, used)
+		m.orig = append(m.orig, raw)
+		off += len(raw)
+	}
+	return m
+}
+
+func (m tabMask) unmaskLine(b []byte, k int) []byte {
+	if k >= len(m.lines) || m.lines[k] == 0 {
+		return b
+	}
+	return bytes.ReplaceAll(b, []byte{m.lines[k]}, []byte{'\t'})
+}
+
+func (m tabMask) unmask(out []byte) []byte {
+	if m.ph != 0 {
+		return bytes.ReplaceAll(out, []byte{m.ph}, []byte{'\t'})
+	}
+	var b []byte
+	for k, raw := range splitLines(out) {
+		if m.skipped[k+1] {
+			raw = m.orig[k]
+		}
+		b = append(b, m.unmaskLine(raw, k)...)
+	}
+	return b
+}
+
+func checkProtected(r Rule, file string, content []byte, opts Options) []Issue {
+	p, ok := protectionFor(file, opts)
+	if !r.Whitespace || !ok {
+		return r.Check(file, content, opts)
+	}
+	m := maskTabs(content, p)
+	var issues []Issue
+	for _, i := range r.Check(file, m.content, opts) {
+		line := i.Line
+		if i.Edit != nil {
+			line = lineOf(content, i.Edit.Start) + 1
+		}
+		if m.skipped[line] {
+			continue
+		}
+		if i.Edit != nil {
+			if m.ph != 0 {
+				i.Edit.NewText = string(m.unmask([]byte(i.Edit.NewText)))
+			} else {
+				i.Edit.NewText = string(m.unmaskLine([]byte(i.Edit.NewText), lineOf(content, i.Edit.Start)))
+			}
+		}
+		issues = append(issues, i)
+	}
+	return issues
+}
+
+func fixProtected(r Rule, content []byte, opts Options) []byte {
+	p, ok := protectionFor(opts.File, opts)
+	if !r.Whitespace || !ok {
+		return r.Fix(content, opts)
+	}
+	m := maskTabs(content, p)
+	return m.unmask(r.Fix(m.content, opts))
+}
+
+func lineOf(content []byte, off int) int {
+	return bytes.Count(content[:min(off, len(content))], []byte("\n"))
+}
+
+func Suppressed(file string, content []byte, opts Options) []Issue {
+	if _, ok := protectionFor(file, opts); !ok || isForeignEncoding(content) {
+		return nil
+	}
+	var out []Issue
+	for _, r := range opts.all() {
+		if !r.Whitespace || !opts.enabled(r) {
+			continue
+		}
+		kept := make(map[Issue]bool)
+		for _, i := range checkProtected(r, file, content, opts) {
+			i.Edit = nil
+			kept[i] = true
+		}
+		for _, i := range r.Check(file, content, opts) {
+			k := i
+			k.Edit = nil
+			if !kept[k] {
+				out = append(out, i)
+			}
+		}
+	}
+	return out
+}
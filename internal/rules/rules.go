@@ -1,24 +1,297 @@
 package rules
 
-import "os"
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"prosefmt/internal/config"
+	"prosefmt/internal/scanner"
+	"strings"
+	"sync"
+)
+
+type Options struct {
+	File            string
+	Root            string
+	Type            string
+	Rules           config.RuleSet
+	Select          Selection
+	ConvertEncoding bool
+	// Extra holds the rules a config adds (plugins, custom rules), kept out
+	// of the registry so each config sees only its own. They run after the
+	// registered rules, before TL001.
+	Extra []Rule
+}
+
+type Rule struct {
+	ID             string
+	Description    string
+	DefaultEnabled bool
+	Severity       Severity
+	Whitespace     bool
+	PartialFix     bool
+	Check          func(file string, content []byte, opts Options) []Issue
+	Fix            func(content []byte, opts Options) []byte
+}
+
+var registryMu sync.RWMutex
+
+// registry is replaced, never modified in place, so a copy of the slice
+// taken under registryMu stays valid.
+var registry = []Rule{
+	{
+		ID:             TL002ID,
+		Description:    "Files must be encoded in UTF-8.",
+		DefaultEnabled: true,
+		Check:          checkTL002,
+		Fix:            fixTL002,
+	},
+	{
+		ID:             TL012ID,
+		Description:    "No ASCII control characters or lone carriage returns.",
+		DefaultEnabled: true,
+		Check:          checkTL012,
+		Fix:            fixTL012,
+	},
+	{
+		ID:             TL010ID,
+		Description:    "No trailing spaces or tabs at the end of a line.",
+		DefaultEnabled: true,
+		Whitespace:     true,
+		Check: func(file string, content []byte, opts Options) []Issue {
+			return checkTL010(file, content, unicodeSpaces(opts))
+		},
+		Fix: func(content []byte, opts Options) []byte {
+			return fixTL010(content, unicodeSpaces(opts))
+		},
+	},
+	{
+		ID:          TL011ID,
+		Description: "No non-ASCII whitespace (optionally no typographic quotes, dashes or ellipsis).",
+		Whitespace:  true,
+		Check:       checkTL011,
+		Fix:         fixTL011,
+	},
+	{
+		ID:          TL020ID,
+		Description: "Lines must be in the configured Unicode normalization form (NFC by default).",
+		Check:       checkTL020,
+		Fix:         fixTL020,
+	},
+	{
+		ID:             TL030ID,
+		Description:    "No leftover merge conflict markers.",
+		DefaultEnabled: true,
+		Check:          checkTL030,
+	},
+	{
+		ID:             TL001ID,
+		Description:    "File must end with exactly one newline (LF or CRLF).",
+		DefaultEnabled: true,
+		Check: func(file string, content []byte, _ Options) []Issue {
+			return CheckTL001(file, content)
+		},
+		Fix: func(content []byte, _ Options) []byte {
+			return FixTL001(content)
+		},
+	},
+}
+
+type FixError struct {
+	Rules  []string
+	Reason string
+}
+
+func (e *FixError) Error() string {
+	if len(e.Rules) == 0 {
+		return e.Reason
+	}
+	return strings.Join(e.Rules, ", ") + ": " + e.Reason
+}
+
+func All() []Rule {
+	return append([]Rule(nil), registered()...)
+}
+
+func registered() []Rule {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry
+}
+
+func (o Options) all() []Rule {
+	r := registered()
+	if len(o.Extra) == 0 {
+		return r
+	}
+	last := len(r) - 1
+	return append(append(r[:last:last], o.Extra...), r[last])
+}
+
+func (o Options) fileType(file string) string {
+	if o.Type != "" {
+		return o.Type
+	}
+	return scanner.DetectType(file, nil, nil)
+}
+
+func (o Options) enabled(r Rule) bool {
+	return o.ruleEnabled(r.ID, r.DefaultEnabled)
+}
+
+func (o Options) ruleEnabled(id string, def bool) bool {
+	return o.Select.Allows(id) && o.Rules.Enabled(id, def)
+}
 
 func Check(file string, content []byte) []Issue {
+	return CheckWith(file, content, Options{})
+}
+
+func CheckWith(file string, content []byte, opts Options) []Issue {
 	var issues []Issue
-	issues = append(issues, CheckTL010(file, content)...)
-	issues = append(issues, CheckTL001(file, content)...)
+	if isForeignEncoding(content) {
+		if tl002Enabled(opts) {
+			issues = withSeverity(checkTL002(file, content, opts), opts.severity(TL002ID, SeverityError))
+		}
+		return issues
+	}
+	for _, r := range opts.all() {
+		if opts.enabled(r) {
+			issues = append(issues, withSeverity(checkProtected(r, file, content, opts), opts.severity(r.ID, r.Severity))...)
+		}
+	}
 	return issues
 }
 
 func Fix(content []byte) []byte {
-	out := FixTL010(content)
-	out = FixTL001(out)
+	return FixWith(content, Options{})
+}
+
+func FixWith(content []byte, opts Options) []byte {
+	out, _ := fixStable(content, opts)
+	return out
+}
+
+func FixChecked(file string, content []byte, opts Options) ([]byte, error) {
+	out, err := fixStable(content, opts)
+	if err != nil || HasConflictMarkers(out) || isForeignEncoding(out) {
+		return out, err
+	}
+	for _, i := range CheckWith(file, out, opts) {
+		if r, ok := opts.lookup(i.RuleID); ok && r.Fix != nil && !r.PartialFix {
+			return out, &FixError{Rules: []string{i.RuleID}, Reason: fmt.Sprintf("still reported after fixing (line %d)", i.Line)}
+		}
+	}
+	return out, nil
+}
+
+func fixStable(content []byte, opts Options) ([]byte, error) {
+	out := content
+	for pass := 0; pass < MaxFixPasses; pass++ {
+		next := fixPass(out, opts)
+		if bytes.Equal(next, out) {
+			return out, nil
+		}
+		out = next
+	}
+	var ids []string
+	for _, r := range opts.all() {
+		if r.Fix != nil && opts.enabled(r) && !bytes.Equal(fixProtected(r, out, opts), out) {
+			ids = append(ids, r.ID)
+		}
+	}
+	return out, &FixError{Rules: ids, Reason: fmt.Sprintf("fixes do not converge after %d passes", MaxFixPasses)}
+}
+
+func fixPass(content []byte, opts Options) []byte {
+	if isForeignEncoding(content) {
+		if !tl002Enabled(opts) {
+			return content
+		}
+		content = fixTL002(content, opts)
+		if isForeignEncoding(content) {
+			return content
+		}
+	}
+	if HasConflictMarkers(content) {
+		return content
+	}
+	out := content
+	for _, r := range opts.all() {
+		if r.Fix != nil && opts.enabled(r) {
+			out = fixProtected(r, out, opts)
+		}
+	}
 	return out
 }
 
 func CheckFile(path string) ([]Issue, error) {
+	return CheckFileWith(path, Options{})
+}
+
+func CheckFileWith(path string, opts Options) ([]Issue, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return Check(path, content), nil
+	return CheckWith(path, content, opts), nil
+}
+
+func HasFixer(id string) bool {
+	return Options{}.HasFixer(id)
+}
+
+func (o Options) HasFixer(id string) bool {
+	r, ok := o.lookup(id)
+	return ok && r.Fix != nil
+}
+
+func (o Options) lookup(id string) (Rule, bool) {
+	for _, r := range o.all() {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func Register(r Rule) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if err := validate(r, registry); err != nil {
+		return err
+	}
+	last := len(registry) - 1
+	registry = append(registry[:last:last], r, registry[last])
+	return nil
+}
+
+// ValidateExtra checks rules meant for Options.Extra: each needs an ID and a
+// Check function, and the IDs must not clash with any other rule.
+func ValidateExtra(extra []Rule) error {
+	known := registered()
+	for _, r := range extra {
+		if err := validate(r, known); err != nil {
+			return err
+		}
+		known = append(known[:len(known):len(known)], r)
+	}
+	return nil
+}
+
+func validate(r Rule, known []Rule) error {
+	if r.ID == "" || r.Check == nil {
+		return fmt.Errorf("rule needs an ID and a Check function")
+	}
+	for _, k := range known {
+		if strings.EqualFold(k.ID, r.ID) {
+			return fmt.Errorf("rule %s is already registered", r.ID)
+		}
+	}
+	for _, p := range pathRegistry {
+		if strings.EqualFold(p.ID, r.ID) {
+			return fmt.Errorf("rule %s is already registered", r.ID)
+		}
+	}
+	return nil
 }
@@ -2,7 +2,15 @@ package rules
 
 import (
 	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"prosefmt/internal/config"
+	"strings"
 	"testing"
+	"unicode"
+	"unicode/utf8"
 )
 
 func TestCheckTL001_NoNewline(t *testing.T) {
@@ -208,3 +216,700 @@ func TestFix_Combined(t *testing.T) {
 		t.Errorf("fixed content should have no issues, got %v", issues)
 	}
 }
+
+func tl011Options(typography bool, allow ...string) Options {
+	on := true
+	return Options{Rules: config.RuleSet{TL011ID: {Enabled: &on, Typography: &typography, Allow: allow}}}
+}
+
+func TestCheckTL011_DisabledByDefault(t *testing.T) {
+	issues := Check("f", []byte("a\u00a0b\n"))
+	if len(issues) != 0 {
+		t.Errorf("expected no issues with TL011 disabled, got %v", issues)
+	}
+}
+
+func TestCheckTL011_NBSP(t *testing.T) {
+	issues := CheckWith("f", []byte("ab\u00a0c\n"), tl011Options(false))
+	if len(issues) != 1 || issues[0].RuleID != TL011ID || issues[0].Line != 1 || issues[0].Column != 3 {
+		t.Errorf("expected one TL011 at line 1 col 3, got %v", issues)
+	}
+}
+
+func TestCheckTL011_TypographyOptional(t *testing.T) {
+	content := []byte("\u201cquoted\u201d \u2014 done\u2026\n")
+	if issues := CheckWith("f", content, tl011Options(false)); len(issues) != 0 {
+		t.Errorf("expected no issues without typography, got %v", issues)
+	}
+	if issues := CheckWith("f", content, tl011Options(true)); len(issues) != 4 {
+		t.Errorf("expected 4 typography issues, got %v", issues)
+	}
+}
+
+func TestCheckTL011_AllowList(t *testing.T) {
+	content := []byte("a\u00a0b\u202fc\n")
+	issues := CheckWith("f", content, tl011Options(false, "U+202F", "\u00a0"))
+	if len(issues) != 0 {
+		t.Errorf("expected allowed characters to pass, got %v", issues)
+	}
+}
+
+func TestFixTL011(t *testing.T) {
+	content := []byte("a\u00a0b\u3000\u2018c\u2019 \u2013 \u2026\n")
+	out := FixWith(content, tl011Options(true))
+	expected := []byte("a b 'c' - ...\n")
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestTL010_UnicodeTrailingWhenTL011Enabled(t *testing.T) {
+	content := []byte("x \u00a0\u2003\n")
+	if issues := CheckTL010("f", content); len(issues) != 0 {
+		t.Errorf("expected TL010 to ignore Unicode whitespace by default, got %v", issues)
+	}
+	issues := CheckWith("f", content, tl011Options(false))
+	var tl010 []Issue
+	for _, i := range issues {
+		if i.RuleID == TL010ID {
+			tl010 = append(tl010, i)
+		}
+	}
+	if len(tl010) != 1 || tl010[0].Column != 2 {
+		t.Errorf("expected one TL010 at col 2, got %v", tl010)
+	}
+	out := FixWith(content, tl011Options(false))
+	if !bytes.Equal(out, []byte("x\n")) {
+		t.Errorf("expected x\\n, got %q", out)
+	}
+}
+
+func TestTL010_UnicodeTrailingFollowsTL011Selection(t *testing.T) {
+	content := []byte("x\u00a0\n")
+	skipped := tl011Options(false)
+	skipped.Select = Selection{Skip: map[string]bool{TL011ID: true}}
+	allowed := tl011Options(false, "U+00A0")
+	for name, opts := range map[string]Options{"skipped": skipped, "allowed": allowed} {
+		if issues := CheckWith("f", content, opts); len(issues) != 0 {
+			t.Errorf("%s: expected no issues, got %v", name, issues)
+		}
+		if out := FixWith(content, opts); !bytes.Equal(out, content) {
+			t.Errorf("%s: expected the NBSP kept, got %q", name, out)
+		}
+	}
+}
+
+func tl020Options(form string) Options {
+	on := true
+	return Options{Rules: config.RuleSet{TL020ID: {Enabled: &on, Form: form}}}
+}
+
+func TestCheckTL020_NFD(t *testing.T) {
+	content := []byte("ok\ncafe\u0301 au lait\n")
+	issues := CheckWith("f", content, tl020Options(""))
+	if len(issues) != 1 || issues[0].RuleID != TL020ID || issues[0].Line != 2 || issues[0].Column != 4 {
+		t.Errorf("expected one TL020 at line 2 col 4, got %v", issues)
+	}
+}
+
+func TestCheckTL020_ConfiguredNFD(t *testing.T) {
+	content := []byte("caf\u00e9\n")
+	issues := CheckWith("f", content, tl020Options("NFD"))
+	if len(issues) != 1 || issues[0].Column != 4 {
+		t.Errorf("expected one TL020 at col 4, got %v", issues)
+	}
+	if issues := CheckWith("f", content, tl020Options("NFC")); len(issues) != 0 {
+		t.Errorf("expected NFC content to pass, got %v", issues)
+	}
+}
+
+func TestFixTL020_PreservesLineEndings(t *testing.T) {
+	content := []byte("e\u0301\r\nA\u030a\r\n")
+	out := FixWith(content, tl020Options(""))
+	expected := []byte("\u00e9\r\n\u00c5\r\n")
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestCheckTL030_Block(t *testing.T) {
+	content := []byte("intro\n<<<<<<< HEAD\nours\n||||||| base\nbase\n=======\ntheirs\n>>>>>>> feature\nend\n")
+	issues := Check("f", content)
+	if len(issues) != 1 || issues[0].RuleID != TL030ID || issues[0].Line != 2 || issues[0].EndLine != 8 {
+		t.Errorf("expected one TL030 for lines 2-8, got %v", issues)
+	}
+}
+
+func TestCheckTL030_SetextHeadingIsNotAConflict(t *testing.T) {
+	content := []byte("Title\n=======\n\ntext\n")
+	if issues := Check("f", content); len(issues) != 0 {
+		t.Errorf("expected no issues for a setext heading, got %v", issues)
+	}
+}
+
+func TestCheckTL030_Unterminated(t *testing.T) {
+	content := []byte("<<<<<<< HEAD\na\n=======\nb\n")
+	issues := Check("f", content)
+	if len(issues) != 1 || issues[0].Line != 1 || issues[0].EndLine != 5 {
+		t.Errorf("expected one unterminated TL030 for lines 1-5, got %v", issues)
+	}
+}
+
+func TestFix_SkipsConflictedContent(t *testing.T) {
+	content := []byte("<<<<<<< HEAD\na  \n=======\nb\t\n>>>>>>> other\n\n\n")
+	out := Fix(content)
+	if !bytes.Equal(out, content) {
+		t.Errorf("expected conflicted content unchanged, got %q", out)
+	}
+}
+
+func TestCheckTL012_ControlCharacters(t *testing.T) {
+	content := []byte("a\x1b[0mb\nbell\x07\n")
+	issues := CheckWith("f.txt", content, Options{})
+	if len(issues) != 2 || issues[0].RuleID != TL012ID || issues[0].Column != 2 || issues[1].Line != 2 || issues[1].Column != 5 {
+		t.Errorf("expected TL012 at 1:2 and 2:5, got %v", issues)
+	}
+}
+
+func TestCheckTL012_LoneCR(t *testing.T) {
+	content := []byte("a\rb\r\n")
+	issues := CheckWith("f.txt", content, Options{})
+	if len(issues) != 1 || issues[0].Column != 2 {
+		t.Errorf("expected one TL012 for lone CR at col 2, got %v", issues)
+	}
+}
+
+func TestCheckTL012_FormFeedAllowedInGo(t *testing.T) {
+	content := []byte("package a\n\f\n")
+	if issues := CheckWith("a.go", content, Options{}); len(issues) != 0 {
+		t.Errorf("expected form feed allowed in Go sources, got %v", issues)
+	}
+	if issues := CheckWith("a.txt", content, Options{}); len(issues) != 1 {
+		t.Errorf("expected form feed reported in text files, got %v", issues)
+	}
+	opts := Options{Rules: config.RuleSet{TL012ID: {Allow: []string{"U+0007"}}}}
+	if issues := CheckWith("a.go", content, opts); len(issues) != 1 {
+		t.Errorf("expected configured allow-list to replace defaults, got %v", issues)
+	}
+}
+
+func TestFixTL012(t *testing.T) {
+	content := []byte("a\x1b \rb\x7f\r\nc\r\n")
+	out := FixWith(content, Options{File: "f.txt"})
+	expected := []byte("a\r\nb\r\nc\r\n")
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func pathIssueIDs(t *testing.T, issues []Issue) map[string]int {
+	ids := make(map[string]int)
+	for _, i := range issues {
+		ids[i.RuleID]++
+		if i.Line != 0 || i.Column != 0 {
+			t.Errorf("path issue should have no line/column, got %v", i)
+		}
+	}
+	return ids
+}
+
+func TestCheckPaths_NameHygiene(t *testing.T) {
+	paths := []string{"notes /a.txt", "docs/a?b.md", "aux.c", "ok/file.txt", "dir./x"}
+	ids := pathIssueIDs(t, CheckPaths(paths, func(string) Options { return Options{} }))
+	if ids[TL040ID] != 2 || ids[TL041ID] != 1 || ids[TL042ID] != 1 || len(ids) != 3 {
+		t.Errorf("expected 2 TL040, 1 TL041, 1 TL042, got %v", ids)
+	}
+}
+
+func TestCheckPaths_CaseCollision(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"README.md", "readme.md", "other.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 3 {
+		t.Skip("file system is case-insensitive")
+	}
+	issues := CheckPaths([]string{filepath.Join(dir, "README.md"), filepath.Join(dir, "other.md")}, func(string) Options { return Options{} })
+	if len(issues) != 1 || issues[0].RuleID != TL043ID || !strings.Contains(issues[0].Message, "readme.md") {
+		t.Errorf("expected one TL043 for README.md, got %v", issues)
+	}
+}
+
+func TestCheckPaths_NFCAndLength(t *testing.T) {
+	on := true
+	opts := Options{Rules: config.RuleSet{TL044ID: {Enabled: &on}, TL045ID: {Max: 8}}}
+	ids := pathIssueIDs(t, CheckPaths([]string{"cafe\u0301.txt"}, func(string) Options { return opts }))
+	if ids[TL044ID] != 1 || ids[TL045ID] != 1 {
+		t.Errorf("expected TL044 and TL045, got %v", ids)
+	}
+}
+
+func TestCheckPaths_RelativeToRoot(t *testing.T) {
+	root := filepath.Join("work dir.", "aux")
+	opts := Options{Root: root, Rules: config.RuleSet{TL045ID: {Max: 12}}}
+	paths := []string{
+		filepath.Join(root, "notes.", "a.md"),
+		filepath.Join(root, "notes.", "b.md"),
+		filepath.Join(root, "c.md"),
+	}
+	issues := CheckPaths(paths, func(string) Options { return opts })
+	if len(issues) != 1 || issues[0].RuleID != TL040ID || issues[0].File != paths[0] || !strings.HasPrefix(issues[0].Message, "directory name") {
+		t.Errorf("expected one TL040 for the directory on the first file, got %v", issues)
+	}
+	opts.Rules = config.RuleSet{TL045ID: {Max: 8}}
+	ids := pathIssueIDs(t, CheckPaths(paths, func(string) Options { return opts }))
+	if ids[TL045ID] != 2 {
+		t.Errorf("expected TL045 for the two nested files only, got %v", ids)
+	}
+}
+
+func TestCheckTL002_NotUTF8(t *testing.T) {
+	content := []byte("caf\xe9  \n\n")
+	issues := Check("f", content)
+	if len(issues) != 1 || issues[0].RuleID != TL002ID || !strings.Contains(issues[0].Message, "iso-8859-1") {
+		t.Errorf("expected only TL002 with detected encoding, got %v", issues)
+	}
+}
+
+func TestCheckTL002_UTF16WithUTF8LikeBytes(t *testing.T) {
+	// "\ud55c\uc5b4\n" in UTF-16LE; the bytes D5 B4 form a valid UTF-8 sequence.
+	content := []byte{0xFF, 0xFE, 0x5C, 0xD5, 0xB4, 0xC5, '\n', 0}
+	issues := Check("f", content)
+	if len(issues) != 1 || issues[0].Message != "file is not UTF-8 (detected utf-16le)" {
+		t.Errorf("expected TL002 to detect UTF-16, got %v", issues)
+	}
+	if out := FixWith(content, Options{ConvertEncoding: true}); string(out) != "\ud55c\uc5b4\n" {
+		t.Errorf("expected converted content, got %q", out)
+	}
+}
+
+func TestFixTL002_ConvertEncoding(t *testing.T) {
+	content := []byte{0xFF, 0xFE, 'a', 0, ' ', 0, '\r', 0, '\n', 0}
+	if out := Fix(content); !bytes.Equal(out, content) {
+		t.Errorf("expected non-UTF-8 content unchanged without conversion, got %q", out)
+	}
+	out := FixWith(content, Options{ConvertEncoding: true})
+	if !bytes.Equal(out, []byte("a\r\n")) {
+		t.Errorf("expected converted and fixed content a\\r\\n, got %q", out)
+	}
+}
+
+func TestCheckTL012_FormFeedAllowedByType(t *testing.T) {
+	content := []byte("x\f\n")
+	if issues := CheckWith("script", content, Options{Type: "python"}); len(issues) != 0 {
+		t.Errorf("expected form feed allowed for python type, got %v", issues)
+	}
+}
+
+func TestProtection_AllPlaceholdersInFile(t *testing.T) {
+	content := []byte("# \x1a\x1c\x1d\x1e\x1f\x01\x02\x03\nall:\n\tgo build \n")
+	opts := Options{File: "Makefile", Select: Selection{Only: map[string]bool{TL010ID: true}}}
+	issues := CheckWith("Makefile", content, opts)
+	if len(issues) != 1 || issues[0].Line != 3 || issues[0].Column != 10 {
+		t.Errorf("expected one TL010 at 3:10, got %v", issues)
+	}
+	out := FixWith(content, opts)
+	if want := "# \x1a\x1c\x1d\x1e\x1f\x01\x02\x03\nall:\n\tgo build\n"; string(out) != want {
+		t.Errorf("expected recipe tab kept and trailing space removed, got %q", out)
+	}
+}
+
+func TestProtection_LineWithAllPlaceholders(t *testing.T) {
+	content := []byte("all:\n\techo \x1a\x1c\x1d\x1e\x1f\x01\x02\x03 \n")
+	opts := Options{File: "Makefile", Select: Selection{Only: map[string]bool{TL010ID: true}}}
+	if issues := CheckWith("Makefile", content, opts); len(issues) != 0 {
+		t.Errorf("expected the unmaskable line to be left alone, got %v", issues)
+	}
+	if out := FixWith(content, opts); !bytes.Equal(out, content) {
+		t.Errorf("expected content unchanged, got %q", out)
+	}
+}
+
+func TestProtection_TSVColumnTabs(t *testing.T) {
+	content := []byte("a\tb\t\n1\t2 \t\n")
+	opts := Options{File: "data.tsv"}
+	issues := CheckWith("data.tsv", content, opts)
+	if len(issues) != 0 {
+		t.Errorf("expected trailing column tabs to be protected, got %v", issues)
+	}
+	if out := FixWith(content, opts); !bytes.Equal(out, content) {
+		t.Errorf("expected TSV unchanged, got %q", out)
+	}
+	suppressed := Suppressed("data.tsv", content, opts)
+	if len(suppressed) != 2 || suppressed[0].RuleID != TL010ID {
+		t.Errorf("expected two suppressed TL010 issues, got %v", suppressed)
+	}
+}
+
+func TestProtection_MakefileRecipeTabs(t *testing.T) {
+	content := []byte("all:  \n\t\n\techo hi \t\n")
+	opts := Options{File: "Makefile", Type: "makefile"}
+	out := FixWith(content, opts)
+	expected := []byte("all:\n\t\n\techo hi\n")
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+	issues := CheckWith("Makefile", content, opts)
+	if len(issues) != 2 || issues[0].Line != 1 || issues[1].Line != 3 {
+		t.Errorf("expected TL010 on lines 1 and 3 only, got %v", issues)
+	}
+}
+
+func TestProtection_NoneForText(t *testing.T) {
+	content := []byte("\t\nx\n")
+	if out := FixWith(content, Options{File: "notes.txt"}); !bytes.Equal(out, []byte("\nx\n")) {
+		t.Errorf("expected tab-only line trimmed in text files, got %q", out)
+	}
+	if s := Suppressed("notes.txt", content, Options{}); len(s) != 0 {
+		t.Errorf("expected nothing suppressed for text files, got %v", s)
+	}
+}
+
+func TestSelection_OnlyAndSkip(t *testing.T) {
+	content := []byte("x  \n\n")
+	only, err := NewSelection([]string{"tl010"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := CheckWith("f", content, Options{Select: only})
+	if len(issues) != 1 || issues[0].RuleID != TL010ID {
+		t.Errorf("expected only TL010, got %v", issues)
+	}
+	if out := FixWith(content, Options{Select: only}); !bytes.Equal(out, []byte("x\n\n")) {
+		t.Errorf("expected only TL010 fix applied, got %q", out)
+	}
+	skip, err := NewSelection(nil, []string{TL010ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues = CheckWith("f", content, Options{Select: skip})
+	if len(issues) != 1 || issues[0].RuleID != TL001ID {
+		t.Errorf("expected only TL001, got %v", issues)
+	}
+}
+
+func TestSelection_UnknownID(t *testing.T) {
+	_, err := NewSelection([]string{"TL999"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "TL999") || !strings.Contains(err.Error(), TL001ID) {
+		t.Errorf("expected unknown ID error listing valid IDs, got %v", err)
+	}
+}
+
+func TestCheckWith_Severity(t *testing.T) {
+	issues := Check("f", []byte("x  \n"))
+	if len(issues) != 1 || issues[0].Severity != SeverityError {
+		t.Fatalf("expected one error by default, got %v", issues)
+	}
+	opts := Options{Rules: config.RuleSet{TL010ID: {Severity: "info"}}}
+	issues = CheckWith("f", []byte("x  \n"), opts)
+	if len(issues) != 1 || issues[0].Severity != SeverityInfo {
+		t.Errorf("expected configured info severity, got %v", issues)
+	}
+}
+
+func TestSeverity_AtLeast(t *testing.T) {
+	if SeverityInfo.AtLeast(SeverityWarning) || !SeverityError.AtLeast(SeverityWarning) || !Severity("").AtLeast(SeverityError) {
+		t.Error("unexpected severity ordering")
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	saved := registry
+	defer func() { registry = saved }()
+	r := Rule{
+		ID:             "X001",
+		DefaultEnabled: true,
+		Check: func(file string, content []byte, _ Options) []Issue {
+			if bytes.Contains(content, []byte("TODO")) {
+				return []Issue{{File: file, Line: 1, Column: 1, RuleID: "X001", Message: "no TODO"}}
+			}
+			return nil
+		},
+		Fix: func(content []byte, _ Options) []byte {
+			return bytes.ReplaceAll(content, []byte("TODO"), []byte("DONE"))
+		},
+	}
+	if err := Register(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(r); err == nil {
+		t.Error("expected duplicate ID error")
+	}
+	if err := Register(Rule{ID: TL010ID, Check: r.Check}); err == nil {
+		t.Error("expected error for a built-in ID")
+	}
+	if registry[len(registry)-1].ID != TL001ID {
+		t.Errorf("TL001 must stay the last fixer, got %s", registry[len(registry)-1].ID)
+	}
+	if issues := Check("f", []byte("TODO\n")); len(issues) != 1 || issues[0].Severity != SeverityError {
+		t.Errorf("expected registered rule to report, got %v", issues)
+	}
+	if out := Fix([]byte("TODO")); string(out) != "DONE\n" {
+		t.Errorf("expected registered fix before TL001, got %q", out)
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	out, err := ApplyEdits([]byte("abcdef"), []TextEdit{{Start: 4, End: 6, NewText: "X"}, {Start: 0, End: 1}, {Start: 2, End: 2, NewText: "-"}})
+	if err != nil || string(out) != "b-cdX" {
+		t.Errorf("got %q, %v", out, err)
+	}
+	_, err = ApplyEdits([]byte("abc"), []TextEdit{{Start: 0, End: 2}, {Start: 1, End: 3, NewText: "x"}})
+	var overlap *OverlapError
+	if !errors.As(err, &overlap) || overlap.A.End != 2 || overlap.B.Start != 1 {
+		t.Errorf("expected overlap error, got %v", err)
+	}
+	if _, err := ApplyEdits([]byte("abc"), []TextEdit{{Start: 2, End: 4}}); err == nil {
+		t.Error("expected error for an edit past the end")
+	}
+}
+
+func TestIssueEdits_TL001TL010(t *testing.T) {
+	content := []byte("a  \r\nb\t\r\n\r\n\r\n")
+	issues := append(CheckTL010("f", content), CheckTL001("f", content)...)
+	want := []TextEdit{{Start: 1, End: 3}, {Start: 6, End: 7}, {Start: 9, End: 13}}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), issues)
+	}
+	for k, w := range want {
+		if issues[k].Edit == nil || *issues[k].Edit != w {
+			t.Errorf("issue %d: got edit %v, want %v", k, issues[k].Edit, w)
+		}
+	}
+	out, err := ApplyEdits(content, []TextEdit{*issues[1].Edit})
+	if err != nil || string(out) != "a  \r\nb\r\n\r\n\r\n" {
+		t.Errorf("applying a single issue's edit: got %q, %v", out, err)
+	}
+	if edit := CheckTL001("f", []byte("x"))[0].Edit; *edit != (TextEdit{Start: 1, End: 1, NewText: "\n"}) {
+		t.Errorf("expected an LF insertion at the end, got %v", edit)
+	}
+}
+
+func TestFixByEdits_ResolvesOverlapsOverPasses(t *testing.T) {
+	check := func(content []byte) []Issue {
+		var issues []Issue
+		for i := 0; i+1 < len(content); i++ {
+			if content[i] == 'a' && content[i+1] == 'a' {
+				issues = append(issues, Issue{Edit: &TextEdit{Start: i, End: i + 2, NewText: "b"}})
+			}
+		}
+		return issues
+	}
+	if out := fixByEdits([]byte("aaaa"), check); string(out) != "bb" {
+		t.Errorf("got %q", out)
+	}
+	if out := fixByEdits([]byte("aaa"), check); string(out) != "ba" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestFixChecked_ReportsRuleThatDoesNotConverge(t *testing.T) {
+	saved := registry
+	defer func() { registry = saved }()
+	swap := strings.NewReplacer("ab", "ba", "ba", "ab")
+	err := Register(Rule{
+		ID:             "X002",
+		DefaultEnabled: true,
+		Check:          func(string, []byte, Options) []Issue { return nil },
+		Fix:            func(content []byte, _ Options) []byte { return []byte(swap.Replace(string(content))) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = FixChecked("f", []byte("ab\n"), Options{})
+	var fe *FixError
+	if !errors.As(err, &fe) || len(fe.Rules) != 1 || fe.Rules[0] != "X002" {
+		t.Errorf("expected X002 to be named, got %v", err)
+	}
+}
+
+func TestFixChecked_ReportsIssuesLeftAfterFixing(t *testing.T) {
+	saved := registry
+	defer func() { registry = saved }()
+	r := Rule{
+		ID:             "X003",
+		DefaultEnabled: true,
+		Check: func(file string, content []byte, _ Options) []Issue {
+			if bytes.Contains(content, []byte("TODO")) {
+				return []Issue{{File: file, Line: 2, Column: 1, RuleID: "X003", Message: "no TODO"}}
+			}
+			return nil
+		},
+		Fix: func(content []byte, _ Options) []byte { return content },
+	}
+	if err := Register(r); err != nil {
+		t.Fatal(err)
+	}
+	out, err := FixChecked("f", []byte("a\nTODO  \n"), Options{})
+	if err == nil || err.Error() != "X003: still reported after fixing (line 2)" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if string(out) != "a\nTODO\n" {
+		t.Errorf("got %q", out)
+	}
+	registry[len(registry)-2].PartialFix = true
+	if _, err := FixChecked("f", []byte("TODO\n"), Options{}); err != nil {
+		t.Errorf("issues of a partial fixer must be allowed, got %v", err)
+	}
+	if _, err := FixChecked("f", []byte("<<<<<<< a\nx  \n=======\n>>>>>>> b\n"), Options{}); err != nil {
+		t.Errorf("files with conflict markers are left alone, got %v", err)
+	}
+}
+
+var propertyOptions = []Options{
+	{},
+	{Rules: config.RuleSet{
+		TL011ID: {Enabled: boolPtr(true), Typography: boolPtr(true)},
+		TL020ID: {Enabled: boolPtr(true)},
+	}},
+	{File: "Makefile", Rules: config.RuleSet{TL011ID: {Enabled: boolPtr(true)}, TL020ID: {Enabled: boolPtr(true), Form: "NFD"}}},
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func checkFixProperties(t *testing.T, content []byte) {
+	t.Helper()
+	for _, opts := range propertyOptions {
+		once := FixWith(content, opts)
+		if twice := FixWith(once, opts); !bytes.Equal(twice, once) {
+			t.Fatalf("fix is not idempotent for %q (file %q):\nonce:  %q\ntwice: %q", content, opts.File, once, twice)
+		}
+		if _, err := FixChecked(opts.File, content, opts); err != nil {
+			t.Fatalf("fix of %q (file %q): %v", content, opts.File, err)
+		}
+	}
+}
+
+func TestFix_Properties(t *testing.T) {
+	pieces := []string{"a", "b", " ", "\t", "\n", "\r", "\r\n", "\x00", "\x1b", "\u00a0", "\u202f", "\u201c", "\u2014", "\u2026",
+		"\u00e9", "e\u0301", "<<<<<<< ", "=======", ">>>>>>> ", "\xff", "\xef\xbb\xbf", "\xfe\xff"}
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		var b strings.Builder
+		for k := rng.Intn(12); k > 0; k-- {
+			b.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		checkFixProperties(t, []byte(b.String()))
+	}
+}
+
+func FuzzFix(f *testing.F) {
+	f.Fuzz(checkFixProperties)
+}
+
+func FuzzSplitLines(f *testing.F) {
+	f.Fuzz(func(t *testing.T, content []byte) {
+		lines := splitLines(content)
+		if len(lines) == 0 || !bytes.Equal(bytes.Join(lines, nil), content) {
+			t.Fatalf("lines %q do not join back to %q", lines, content)
+		}
+		for k, raw := range lines {
+			line, ending := stripLineEnding(raw)
+			if !bytes.Equal(append(append([]byte(nil), line...), ending...), raw) {
+				t.Fatalf("line %d: %q + %q != %q", k, line, ending, raw)
+			}
+			if bytes.IndexByte(line, '\n') >= 0 {
+				t.Fatalf("line %d: %q contains a line feed", k, line)
+			}
+			if k < len(lines)-1 && len(ending) == 0 || k == len(lines)-1 && len(ending) > 0 {
+				t.Fatalf("line %d of %d: unexpected ending %q", k, len(lines), ending)
+			}
+		}
+		le := detectLineEnding(content)
+		if first := bytes.IndexByte(content, '\n'); first < 0 && le != lineEndLF || first >= 0 && (le == lineEndCRLF) != (first > 0 && content[first-1] == '\r') {
+			t.Fatalf("detected %q for %q", le, content)
+		}
+	})
+}
+
+func FuzzTL001(f *testing.F) {
+	f.Fuzz(func(t *testing.T, content []byte) {
+		issues := CheckTL001("f", content)
+		if len(content) == 0 && len(issues) > 0 || len(issues) > 1 {
+			t.Fatalf("unexpected issues %v for %q", issues, content)
+		}
+		for _, i := range issues {
+			if i.Edit == nil || i.Edit.Start < 0 || i.Edit.Start > i.Edit.End || i.Edit.End != len(content) {
+				t.Fatalf("edit %v out of bounds for %q", i.Edit, content)
+			}
+		}
+		out := FixTL001(content)
+		if got := CheckTL001("f", out); len(got) > 0 {
+			t.Fatalf("fixed %q to %q, still reported: %v", content, out, got)
+		}
+		if !bytes.Equal(bytes.TrimRight(out, "\r\n"), bytes.TrimRight(content, "\r\n")) {
+			t.Fatalf("fix changed more than the end of %q: %q", content, out)
+		}
+		if len(content) > 0 && !bytes.HasSuffix(out, []byte(detectLineEnding(content))) {
+			t.Fatalf("fix of %q does not end with %q: %q", content, detectLineEnding(content), out)
+		}
+	})
+}
+
+func FuzzRules(f *testing.F) {
+	whitespaceOnly := map[string]bool{TL001ID: true, TL010ID: true}
+	stripSpace := func(b []byte) []byte {
+		return bytes.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, b)
+	}
+	f.Fuzz(func(t *testing.T, content []byte) {
+		lines := splitLines(content)
+		for _, opts := range propertyOptions {
+			for _, r := range registry {
+				for _, i := range checkProtected(r, opts.File, content, opts) {
+					if i.Line < 0 || i.Line > len(lines) || i.EndLine != 0 && (i.EndLine < i.Line || i.EndLine > len(lines)) {
+						t.Fatalf("%s: line %d-%d out of range for %q", r.ID, i.Line, i.EndLine, content)
+					}
+					if i.Line > 0 && (i.Column < 1 || i.Column > len(lines[i.Line-1])+1) {
+						t.Fatalf("%s: column %d out of range on line %d of %q", r.ID, i.Column, i.Line, content)
+					}
+					if e := i.Edit; e != nil && (e.Start < 0 || e.Start > e.End || e.End > len(content)) {
+						t.Fatalf("%s: edit %v out of bounds for %q", r.ID, *e, content)
+					}
+				}
+				if r.Fix == nil {
+					continue
+				}
+				out := fixProtected(r, content, opts)
+				if whitespaceOnly[r.ID] && !bytes.Equal(stripSpace(out), stripSpace(content)) {
+					t.Fatalf("%s: fix changed more than whitespace: %q -> %q", r.ID, content, out)
+				}
+				if utf8.Valid(content) && !utf8.Valid(out) {
+					t.Fatalf("%s: fix broke UTF-8: %q -> %q", r.ID, content, out)
+				}
+			}
+		}
+	})
+}
+
+func TestChanges(t *testing.T) {
+	enabled := true
+	opts := Options{Rules: config.RuleSet{TL011ID: {Enabled: &enabled}}}
+	content := []byte("a\u00a0b\u00a0c  \nok\n\n")
+	changes := Changes("f", content, opts)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	if c := changes[0]; c.RuleID != TL011ID || len(c.Issues) != 2 || c.Edit != (TextEdit{Start: 0, End: 10, NewText: "a b c  \n"}) {
+		t.Errorf("expected one TL011 line change for both issues, got %+v", c)
+	}
+	if c := changes[1]; c.RuleID != TL010ID || c.Edit != (TextEdit{Start: 7, End: 9}) {
+		t.Errorf("expected the TL010 issue edit, got %+v", c)
+	}
+	if c := changes[2]; c.RuleID != TL001ID || c.Edit != (TextEdit{Start: 13, End: 14}) {
+		t.Errorf("expected the TL001 issue edit, got %+v", c)
+	}
+	if changes := Changes("f", []byte("<<<<<<< a\nx \n=======\n>>>>>>> b\n"), Options{}); len(changes) != 0 {
+		t.Errorf("expected no changes with conflict markers, got %+v", changes)
+	}
+}
@@ -0,0 +1,61 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Selection struct {
+	Only map[string]bool
+	Skip map[string]bool
+}
+
+func NewSelection(only, skip []string, extra ...Rule) (Selection, error) {
+	if err := ValidateIDs(append(append([]string(nil), only...), skip...), extra...); err != nil {
+		return Selection{}, err
+	}
+	s := Selection{Skip: make(map[string]bool)}
+	if len(only) > 0 {
+		s.Only = make(map[string]bool)
+		for _, id := range only {
+			s.Only[strings.ToUpper(id)] = true
+		}
+	}
+	for _, id := range skip {
+		s.Skip[strings.ToUpper(id)] = true
+	}
+	return s, nil
+}
+
+func (s Selection) Allows(id string) bool {
+	if s.Skip[id] {
+		return false
+	}
+	return s.Only == nil || s.Only[id]
+}
+
+func IDs(extra ...Rule) []string {
+	var ids []string
+	for _, r := range append(All(), extra...) {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range pathRegistry {
+		ids = append(ids, r.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func ValidateIDs(ids []string, extra ...Rule) error {
+	known := make(map[string]bool)
+	for _, id := range IDs(extra...) {
+		known[id] = true
+	}
+	for _, id := range ids {
+		if !known[strings.ToUpper(id)] {
+			return fmt.Errorf("unknown rule ID %q (valid: %s)", id, strings.Join(IDs(extra...), ", "))
+		}
+	}
+	return nil
+}
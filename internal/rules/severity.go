@@ -0,0 +1,57 @@
+package rules
+
+import "fmt"
+
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+func ParseSeverity(s string) (Severity, error) {
+	switch Severity(s) {
+	case SeverityInfo, SeverityWarning, SeverityError:
+		return Severity(s), nil
+	}
+	return "", fmt.Errorf("unknown severity %q (want info, warning or error)", s)
+}
+
+func (s Severity) String() string {
+	if s == "" {
+		return string(SeverityError)
+	}
+	return string(s)
+}
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityInfo:
+		return 0
+	case SeverityWarning:
+		return 1
+	}
+	return 2
+}
+
+func (s Severity) AtLeast(t Severity) bool {
+	return s.rank() >= t.rank()
+}
+
+func (o Options) severity(id string, def Severity) Severity {
+	if s := o.Rules.Get(id).Severity; s != "" {
+		return Severity(s)
+	}
+	if def != "" {
+		return def
+	}
+	return SeverityError
+}
+
+func withSeverity(issues []Issue, s Severity) []Issue {
+	for i := range issues {
+		issues[i].Severity = s
+	}
+	return issues
+}
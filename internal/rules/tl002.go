@@ -0,0 +1,52 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"prosefmt/internal/charset"
+	"unicode/utf8"
+)
+
+const TL002ID = "TL002"
+
+func checkTL002(file string, content []byte, _ Options) []Issue {
+	if !isForeignEncoding(content) {
+		return nil
+	}
+	if enc, ok := charset.DetectUTF16(content); ok {
+		return []Issue{{File: file, Line: 1, Column: 1, RuleID: TL002ID, Message: fmt.Sprintf("file is not UTF-8 (detected %s)", enc)}}
+	}
+	if off, ok := charset.InvalidUTF8(content); ok {
+		line := 1 + bytes.Count(content[:off], []byte("\n"))
+		col := off - bytes.LastIndexByte(content[:off], '\n')
+		return []Issue{{File: file, Line: line, Column: col, RuleID: TL002ID, Message: fmt.Sprintf("invalid UTF-8 (byte 0x%02X); not converting a file that is mostly UTF-8", content[off])}}
+	}
+	msg := "file is not UTF-8 (encoding not recognized)"
+	if enc, ok := charset.Detect(content); ok {
+		msg = fmt.Sprintf("file is not UTF-8 (detected %s)", enc)
+	}
+	return []Issue{{File: file, Line: 1, Column: 1, RuleID: TL002ID, Message: msg}}
+}
+
+func fixTL002(content []byte, opts Options) []byte {
+	if !opts.ConvertEncoding || !isForeignEncoding(content) {
+		return content
+	}
+	enc, ok := charset.Detect(content)
+	if !ok {
+		return content
+	}
+	out, err := charset.ToUTF8(content, enc)
+	if err != nil {
+		return content
+	}
+	return out
+}
+
+func isForeignEncoding(content []byte) bool {
+	return !utf8.Valid(content)
+}
+
+func tl002Enabled(opts Options) bool {
+	return opts.ruleEnabled(TL002ID, true)
+}
@@ -1,17 +1,24 @@
 package rules
 
+import "unicode/utf8"
+
 const (
 	TL010ID  = "TL010"
 	TL010Msg = "no trailing spaces at end of line"
 )
 
 func CheckTL010(file string, content []byte) []Issue {
+	return checkTL010(file, content, nil)
+}
+
+func checkTL010(file string, content []byte, spaces map[rune]bool) []Issue {
 	var issues []Issue
 	lines := splitLines(content)
+	off := 0
 	for lineNum, raw := range lines {
 		line := lineNum + 1
 		contentPart, _ := stripLineEnding(raw)
-		trailingStart := trailingSpaceStart(contentPart)
+		trailingStart := trailingSpaceStart(contentPart, spaces)
 		if trailingStart >= 0 {
 			issues = append(issues, Issue{
 				File:    file,
@@ -19,8 +26,10 @@ func CheckTL010(file string, content []byte) []Issue {
 				Column:  trailingStart + 1,
 				RuleID:  TL010ID,
 				Message: TL010Msg,
+				Edit:    &TextEdit{Start: off + trailingStart, End: off + len(contentPart)},
 			})
 		}
+		off += len(raw)
 	}
 	return issues
 }
@@ -35,11 +44,8 @@ func stripLineEnding(raw []byte) (content []byte, ending []byte) {
 	return raw, nil
 }
 
-func trailingSpaceStart(content []byte) int {
-	i := len(content)
-	for i > 0 && (content[i-1] == ' ' || content[i-1] == '\t') {
-		i--
-	}
+func trailingSpaceStart(content []byte, spaces map[rune]bool) int {
+	i := len(trimTrailingSpaces(content, spaces))
 	if i < len(content) {
 		return i
 	}
@@ -47,21 +53,27 @@ func trailingSpaceStart(content []byte) int {
 }
 
 func FixTL010(content []byte) []byte {
-	lines := splitLines(content)
-	var out []byte
-	for _, raw := range lines {
-		contentPart, ending := stripLineEnding(raw)
-		trimmed := trimTrailingSpaces(contentPart)
-		out = append(out, trimmed...)
-		out = append(out, ending...)
-	}
-	return out
+	return fixTL010(content, nil)
+}
+
+func fixTL010(content []byte, spaces map[rune]bool) []byte {
+	return fixByEdits(content, func(content []byte) []Issue {
+		return checkTL010("", content, spaces)
+	})
 }
 
-func trimTrailingSpaces(b []byte) []byte {
+func trimTrailingSpaces(b []byte, spaces map[rune]bool) []byte {
 	i := len(b)
-	for i > 0 && (b[i-1] == ' ' || b[i-1] == '\t') {
-		i--
+	for i > 0 {
+		if b[i-1] == ' ' || b[i-1] == '\t' {
+			i--
+			continue
+		}
+		r, size := utf8.DecodeLastRune(b[:i])
+		if !spaces[r] {
+			break
+		}
+		i -= size
 	}
 	return b[:i]
 }
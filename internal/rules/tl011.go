@@ -0,0 +1,146 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const TL011ID = "TL011"
+
+type replacement struct {
+	name  string
+	ascii string
+}
+
+var nonASCIISpaces = map[rune]replacement{
+	'\u00a0': {"NO-BREAK SPACE", " "},
+	'\u1680': {"OGHAM SPACE MARK", " "},
+	'\u2000': {"EN QUAD", " "},
+	'\u2001': {"EM QUAD", " "},
+	'\u2002': {"EN SPACE", " "},
+	'\u2003': {"EM SPACE", " "},
+	'\u2004': {"THREE-PER-EM SPACE", " "},
+	'\u2005': {"FOUR-PER-EM SPACE", " "},
+	'\u2006': {"SIX-PER-EM SPACE", " "},
+	'\u2007': {"FIGURE SPACE", " "},
+	'\u2008': {"PUNCTUATION SPACE", " "},
+	'\u2009': {"THIN SPACE", " "},
+	'\u200a': {"HAIR SPACE", " "},
+	'\u202f': {"NARROW NO-BREAK SPACE", " "},
+	'\u205f': {"MEDIUM MATHEMATICAL SPACE", " "},
+	'\u3000': {"IDEOGRAPHIC SPACE", " "},
+}
+
+var typographic = map[rune]replacement{
+	'\u2010': {"HYPHEN", "-"},
+	'\u2011': {"NON-BREAKING HYPHEN", "-"},
+	'\u2013': {"EN DASH", "-"},
+	'\u2014': {"EM DASH", "--"},
+	'\u2018': {"LEFT SINGLE QUOTATION MARK", "'"},
+	'\u2019': {"RIGHT SINGLE QUOTATION MARK", "'"},
+	'\u201a': {"SINGLE LOW-9 QUOTATION MARK", "'"},
+	'\u201b': {"SINGLE HIGH-REVERSED-9 QUOTATION MARK", "'"},
+	'\u201c': {"LEFT DOUBLE QUOTATION MARK", "\""},
+	'\u201d': {"RIGHT DOUBLE QUOTATION MARK", "\""},
+	'\u201e': {"DOUBLE LOW-9 QUOTATION MARK", "\""},
+	'\u201f': {"DOUBLE HIGH-REVERSED-9 QUOTATION MARK", "\""},
+	'\u2026': {"HORIZONTAL ELLIPSIS", "..."},
+}
+
+// unicodeSpaces returns the non-ASCII spaces TL010 trims when TL011 runs,
+// leaving out those TL011 allows.
+func unicodeSpaces(opts Options) map[rune]bool {
+	if !opts.ruleEnabled(TL011ID, false) {
+		return nil
+	}
+	allow := tl011Config(opts).allow
+	spaces := make(map[rune]bool)
+	for r := range nonASCIISpaces {
+		if !allow[r] {
+			spaces[r] = true
+		}
+	}
+	return spaces
+}
+
+type tl011Settings struct {
+	typography bool
+	allow      map[rune]bool
+}
+
+func tl011Config(opts Options) tl011Settings {
+	rc := opts.Rules.Get(TL011ID)
+	s := tl011Settings{allow: ParseAllowList(rc.Allow)}
+	if rc.Typography != nil {
+		s.typography = *rc.Typography
+	}
+	return s
+}
+
+func ParseAllowList(entries []string) map[rune]bool {
+	allow := make(map[rune]bool)
+	for _, e := range entries {
+		if hex, ok := strings.CutPrefix(strings.ToUpper(e), "U+"); ok {
+			if n, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				allow[rune(n)] = true
+				continue
+			}
+		}
+		for _, r := range e {
+			allow[r] = true
+		}
+	}
+	return allow
+}
+
+func (s tl011Settings) lookup(r rune) (replacement, bool) {
+	if s.allow[r] {
+		return replacement{}, false
+	}
+	if rep, ok := nonASCIISpaces[r]; ok {
+		return rep, true
+	}
+	if s.typography {
+		rep, ok := typographic[r]
+		return rep, ok
+	}
+	return replacement{}, false
+}
+
+func checkTL011(file string, content []byte, opts Options) []Issue {
+	s := tl011Config(opts)
+	var issues []Issue
+	for lineNum, raw := range splitLines(content) {
+		for col := 0; col < len(raw); {
+			r, size := utf8.DecodeRune(raw[col:])
+			if rep, ok := s.lookup(r); ok {
+				issues = append(issues, Issue{
+					File:    file,
+					Line:    lineNum + 1,
+					Column:  col + 1,
+					RuleID:  TL011ID,
+					Message: fmt.Sprintf("non-ASCII character U+%04X (%s), use '%s'", r, rep.name, rep.ascii),
+				})
+			}
+			col += size
+		}
+	}
+	return issues
+}
+
+func fixTL011(content []byte, opts Options) []byte {
+	s := tl011Config(opts)
+	out := make([]byte, 0, len(content))
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if rep, ok := s.lookup(r); ok {
+			out = append(out, rep.ascii...)
+		} else {
+			out = append(out, content[i:i+size]...)
+		}
+		i += size
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package rules
+
+import "fmt"
+
+const TL012ID = "TL012"
+
+var controlNames = [...]string{
+	"NUL", "SOH", "STX", "ETX", "EOT", "ENQ", "ACK", "BEL",
+	"BS", "HT", "LF", "VT", "FF", "CR", "SO", "SI",
+	"DLE", "DC1", "DC2", "DC3", "DC4", "NAK", "SYN", "ETB",
+	"CAN", "EM", "SUB", "ESC", "FS", "GS", "RS", "US",
+}
+
+var formFeedTypes = map[string]bool{
+	"go": true, "c": true, "cpp": true, "python": true, "lisp": true,
+}
+
+func tl012Allow(file string, opts Options) map[rune]bool {
+	rc := opts.Rules.Get(TL012ID)
+	if rc.Allow != nil {
+		return ParseAllowList(rc.Allow)
+	}
+	allow := make(map[rune]bool)
+	if formFeedTypes[opts.fileType(file)] {
+		allow['\f'] = true
+	}
+	return allow
+}
+
+func isControl(c byte) bool {
+	return (c < 0x20 && c != '\t' && c != '\n') || c == 0x7f
+}
+
+func controlName(c byte) string {
+	if c == 0x7f {
+		return "DEL"
+	}
+	return controlNames[c]
+}
+
+func checkTL012(file string, content []byte, opts Options) []Issue {
+	allow := tl012Allow(file, opts)
+	var issues []Issue
+	for lineNum, raw := range splitLines(content) {
+		contentPart, _ := stripLineEnding(raw)
+		for col, c := range contentPart {
+			if !isControl(c) || allow[rune(c)] {
+				continue
+			}
+			msg := fmt.Sprintf("control character U+%04X (%s)", c, controlName(c))
+			if c == '\r' {
+				msg = "lone carriage return (CR) is not a line break"
+			}
+			issues = append(issues, Issue{
+				File:    file,
+				Line:    lineNum + 1,
+				Column:  col + 1,
+				RuleID:  TL012ID,
+				Message: msg,
+			})
+		}
+	}
+	return issues
+}
+
+func fixTL012(content []byte, opts Options) []byte {
+	allow := tl012Allow(opts.File, opts)
+	le := detectLineEnding(content)
+	var out []byte
+	for _, raw := range splitLines(content) {
+		contentPart, ending := stripLineEnding(raw)
+		for _, c := range contentPart {
+			switch {
+			case !isControl(c) || allow[rune(c)]:
+				out = append(out, c)
+			case c == '\r':
+				out = append(out, le...)
+			}
+		}
+		out = append(out, ending...)
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package rules
+
+import (
+	"fmt"
+	"prosefmt/internal/norm"
+)
+
+const TL020ID = "TL020"
+
+func normForm(opts Options) norm.Form {
+	f, err := norm.ParseForm(opts.Rules.Get(TL020ID).Form)
+	if err != nil {
+		return norm.NFC
+	}
+	return f
+}
+
+func checkTL020(file string, content []byte, opts Options) []Issue {
+	form := normForm(opts)
+	var issues []Issue
+	for lineNum, raw := range splitLines(content) {
+		contentPart, _ := stripLineEnding(raw)
+		if form.IsNormal(contentPart) {
+			continue
+		}
+		issues = append(issues, Issue{
+			File:    file,
+			Line:    lineNum + 1,
+			Column:  firstDifference(contentPart, form.Bytes(contentPart)) + 1,
+			RuleID:  TL020ID,
+			Message: fmt.Sprintf("line is not in Unicode %s normalization form", form),
+		})
+	}
+	return issues
+}
+
+func firstDifference(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	for i > 0 && i < len(a) && a[i]&0xC0 == 0x80 {
+		i--
+	}
+	return i
+}
+
+func fixTL020(content []byte, opts Options) []byte {
+	form := normForm(opts)
+	var out []byte
+	for _, raw := range splitLines(content) {
+		contentPart, ending := stripLineEnding(raw)
+		out = append(out, form.Bytes(contentPart)...)
+		out = append(out, ending...)
+	}
+	return out
+}
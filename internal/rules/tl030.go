@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const TL030ID = "TL030"
+
+type conflictBlock struct {
+	start, end int
+	closed     bool
+}
+
+func isMarker(line []byte, marker string, withLabel bool) bool {
+	if !bytes.HasPrefix(line, []byte(marker)) {
+		return false
+	}
+	rest := line[len(marker):]
+	if len(rest) == 0 {
+		return true
+	}
+	return withLabel && rest[0] == ' '
+}
+
+func conflictBlocks(content []byte) []conflictBlock {
+	var blocks []conflictBlock
+	start, sep := 0, 0
+	for lineNum, raw := range splitLines(content) {
+		line, _ := stripLineEnding(raw)
+		n := lineNum + 1
+		switch {
+		case isMarker(line, "<<<<<<<", true):
+			if start > 0 && sep > 0 {
+				blocks = append(blocks, conflictBlock{start: start, end: n - 1})
+			}
+			start, sep = n, 0
+		case start > 0 && sep == 0 && isMarker(line, "|||||||", true):
+		case start > 0 && isMarker(line, "=======", false):
+			sep = n
+		case start > 0 && sep > 0 && isMarker(line, ">>>>>>>", true):
+			blocks = append(blocks, conflictBlock{start: start, end: n, closed: true})
+			start, sep = 0, 0
+		}
+	}
+	if start > 0 && sep > 0 {
+		blocks = append(blocks, conflictBlock{start: start, end: len(splitLines(content))})
+	}
+	return blocks
+}
+
+func HasConflictMarkers(content []byte) bool {
+	return len(conflictBlocks(content)) > 0
+}
+
+func checkTL030(file string, content []byte, _ Options) []Issue {
+	var issues []Issue
+	for _, b := range conflictBlocks(content) {
+		msg := fmt.Sprintf("merge conflict markers (lines %d-%d)", b.start, b.end)
+		if !b.closed {
+			msg = fmt.Sprintf("unterminated merge conflict markers (lines %d-%d)", b.start, b.end)
+		}
+		issues = append(issues, Issue{
+			File:    file,
+			Line:    b.start,
+			Column:  1,
+			EndLine: b.end,
+			RuleID:  TL030ID,
+			Message: msg,
+		})
+	}
+	return issues
+}
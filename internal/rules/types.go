@@ -1,9 +1,12 @@
 package rules
 
 type Issue struct {
-	File    string
-	Line    int
-	Column  int
-	RuleID  string
-	Message string
+	File     string
+	Line     int
+	Column   int
+	EndLine  int
+	RuleID   string
+	Severity Severity
+	Message  string
+	Edit     *TextEdit
 }
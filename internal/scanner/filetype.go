@@ -0,0 +1,189 @@
+package scanner
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const TypeText = "text"
+
+var extensionTypes = map[string]string{
+	".go":       "go",
+	".c":        "c",
+	".h":        "c",
+	".cc":       "cpp",
+	".cpp":      "cpp",
+	".cxx":      "cpp",
+	".hh":       "cpp",
+	".hpp":      "cpp",
+	".py":       "python",
+	".rb":       "ruby",
+	".pl":       "perl",
+	".rs":       "rust",
+	".java":     "java",
+	".js":       "javascript",
+	".mjs":      "javascript",
+	".ts":       "typescript",
+	".sh":       "shell",
+	".bash":     "shell",
+	".zsh":      "shell",
+	".mk":       "makefile",
+	".md":       "markdown",
+	".markdown": "markdown",
+	".rst":      "rst",
+	".adoc":     "asciidoc",
+	".txt":      "text",
+	".tsv":      "tsv",
+	".csv":      "csv",
+	".json":     "json",
+	".yaml":     "yaml",
+	".yml":      "yaml",
+	".toml":     "toml",
+	".xml":      "xml",
+	".html":     "html",
+	".htm":      "html",
+	".css":      "css",
+	".el":       "lisp",
+	".lisp":     "lisp",
+	".scm":      "lisp",
+	".pkl":      "pkl",
+	".diff":     "diff",
+	".patch":    "diff",
+}
+
+var filenameTypes = map[string]string{
+	"makefile":       "makefile",
+	"gnumakefile":    "makefile",
+	"dockerfile":     "dockerfile",
+	"containerfile":  "dockerfile",
+	"cmakelists.txt": "cmake",
+	"go.mod":         "gomod",
+	"go.sum":         "gosum",
+	"gemfile":        "ruby",
+	"rakefile":       "ruby",
+	"license":        "text",
+	".gitignore":     "gitignore",
+	".gitattributes": "gitattributes",
+	".editorconfig":  "editorconfig",
+}
+
+var typeAliases = map[string]string{
+	"sh":          "shell",
+	"bash":        "shell",
+	"zsh":         "shell",
+	"dash":        "shell",
+	"make":        "makefile",
+	"py":          "python",
+	"c++":         "cpp",
+	"md":          "markdown",
+	"emacs-lisp":  "lisp",
+	"scheme":      "lisp",
+	"js":          "javascript",
+	"node":        "javascript",
+	"ts":          "typescript",
+	"golang":      "go",
+	"yml":         "yaml",
+	"conf":        "text",
+	"fundamental": "text",
+}
+
+var (
+	vimModeline   = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex):.*\b(?:ft|filetype)=([\w+-]+)`)
+	emacsModeline = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+	emacsMode     = regexp.MustCompile(`(?i)(?:^|;)\s*mode:\s*([\w+-]+)`)
+	versionSuffix = regexp.MustCompile(`[\d.]+$`)
+)
+
+// DetectType guesses the file type from modelines, the file name and the
+// shebang line. head is the start of the file; tail is its end when head
+// does not hold the whole file, or nil.
+func DetectType(path string, head, tail []byte) string {
+	if t := modelineType(head, tail); t != "" {
+		return t
+	}
+	base := strings.ToLower(filepath.Base(path))
+	if t, ok := filenameTypes[base]; ok {
+		return t
+	}
+	if t, ok := extensionTypes[strings.ToLower(filepath.Ext(base))]; ok {
+		return t
+	}
+	if t := shebangType(head); t != "" {
+		return t
+	}
+	return TypeText
+}
+
+func normalizeType(name string) string {
+	name = strings.ToLower(name)
+	if t, ok := typeAliases[name]; ok {
+		return t
+	}
+	return name
+}
+
+// HeadTail splits content into the parts DetectType looks at, the way the
+// scanner reads them from a file.
+func HeadTail(content []byte) (head, tail []byte) {
+	if len(content) <= maxScanBytes {
+		return content, nil
+	}
+	return content[:maxScanBytes], content[len(content)-maxTailBytes:]
+}
+
+func modelineType(head, tail []byte) string {
+	lines := bytes.Split(head, []byte("\n"))
+	var candidates [][]byte
+	switch {
+	case tail != nil:
+		last := bytes.Split(tail, []byte("\n"))[1:]
+		candidates = append(append(candidates, lines[:min(5, len(lines))]...), last[max(0, len(last)-5):]...)
+	case len(lines) <= 10:
+		candidates = lines
+	default:
+		candidates = append(append(candidates, lines[:5]...), lines[len(lines)-5:]...)
+	}
+	for _, line := range candidates {
+		if m := vimModeline.FindSubmatch(line); m != nil {
+			return normalizeType(string(m[1]))
+		}
+		if m := emacsModeline.FindSubmatch(line); m != nil {
+			vars := string(m[1])
+			if !strings.Contains(vars, ":") {
+				return normalizeType(strings.TrimSpace(vars))
+			}
+			if mm := emacsMode.FindStringSubmatch(vars); mm != nil {
+				return normalizeType(mm[1])
+			}
+		}
+	}
+	return ""
+}
+
+func shebangType(head []byte) string {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return ""
+	}
+	line, _, _ := bytes.Cut(head[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interp = f
+				break
+			}
+		}
+	}
+	interp = versionSuffix.ReplaceAllString(interp, "")
+	if interp == "" {
+		return ""
+	}
+	return normalizeType(interp)
+}
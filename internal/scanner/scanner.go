@@ -1,56 +1,129 @@
 package scanner
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"prosefmt/internal/charset"
+	"strings"
 	"unicode/utf8"
 )
 
-const maxScanBytes = 32 * 1024
+const (
+	maxScanBytes = 32 * 1024
+	maxTailBytes = 4 * 1024
+)
+
+var ignoredDirs = map[string]bool{
+	".prosefmt": true,
+}
+
+type File struct {
+	Path     string
+	Root     string
+	Encoding string
+	Type     string
+}
+
+type Options struct {
+	MaxFileSize int64
+	OnError     func(path string, err error)
+}
+
+var magicNumbers = []struct {
+	prefix []byte
+	name   string
+}{
+	{[]byte("\x89PNG\r\n\x1a\n"), "PNG image"},
+	{[]byte("%PDF-"), "PDF document"},
+	{[]byte("PK\x03\x04"), "ZIP archive"},
+	{[]byte("PK\x05\x06"), "ZIP archive"},
+	{[]byte("\x7fELF"), "ELF binary"},
+	{[]byte("\x1f\x8b"), "gzip archive"},
+}
+
+type NotTextError struct {
+	Reason string
+}
+
+func (e *NotTextError) Error() string {
+	return "not a text file: " + e.Reason
+}
 
 func Scan(paths []string) ([]string, map[string]string, error) {
-	var out []string
+	files, skipped, err := ScanFiles(paths, Options{})
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make([]string, 0, len(files))
+	for _, f := range files {
+		out = append(out, f.Path)
+	}
+	return out, skipped, nil
+}
+
+func ScanFiles(paths []string, opts Options) ([]File, map[string]string, error) {
+	var out []File
 	skipped := make(map[string]string)
 	seen := make(map[string]bool)
+	visit := func(p, root string, fi os.FileInfo) {
+		abs, _ := filepath.Abs(p)
+		if seen[abs] {
+			return
+		}
+		seen[abs] = true
+		if opts.MaxFileSize > 0 && fi.Size() > opts.MaxFileSize {
+			skipped[p] = fmt.Sprintf("larger than max file size (%d > %d bytes)", fi.Size(), opts.MaxFileSize)
+			return
+		}
+		f, reason, err := detectFile(p)
+		switch {
+		case err != nil:
+			if opts.OnError != nil {
+				opts.OnError(p, err)
+			}
+		case reason != "":
+			skipped[p] = reason
+		default:
+			f.Root = root
+			out = append(out, f)
+		}
+	}
 	for _, root := range paths {
 		info, err := os.Stat(root)
 		if err != nil {
+			if opts.OnError != nil {
+				opts.OnError(root, err)
+				continue
+			}
 			return nil, nil, err
 		}
 		if info.Mode().IsRegular() {
-			abs, _ := filepath.Abs(root)
-			if seen[abs] {
-				continue
-			}
-			seen[abs] = true
-			ok, reason := isTextFileWithReason(root)
-			if ok {
-				out = append(out, root)
-			} else if reason != "" {
-				skipped[root] = reason
-			}
+			visit(root, filepath.Dir(root), info)
 			continue
 		}
 		if info.IsDir() {
 			err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
 				if err != nil {
-					return err
-				}
-				if !fi.Mode().IsRegular() {
+					if opts.OnError == nil {
+						return err
+					}
+					opts.OnError(p, err)
+					if fi != nil && fi.IsDir() {
+						return filepath.SkipDir
+					}
 					return nil
 				}
-				abs, _ := filepath.Abs(p)
-				if seen[abs] {
-					return nil
+				if fi.IsDir() && p != root && ignoredDirs[fi.Name()] {
+					return filepath.SkipDir
 				}
-				seen[abs] = true
-				ok, reason := isTextFileWithReason(p)
-				if ok {
-					out = append(out, p)
-				} else if reason != "" {
-					skipped[p] = reason
+				if !fi.Mode().IsRegular() {
+					return nil
 				}
+				visit(p, root, fi)
 				return nil
 			})
 			if err != nil {
@@ -61,33 +134,108 @@ func Scan(paths []string) ([]string, map[string]string, error) {
 	return out, skipped, nil
 }
 
+func Validate(content []byte) (string, error) {
+	enc, reason := classify(content, false)
+	if reason != "" {
+		return "", &NotTextError{Reason: reason}
+	}
+	return enc, nil
+}
+
+func IsNotText(err error) bool {
+	var e *NotTextError
+	return errors.As(err, &e)
+}
+
 func isTextFile(path string) bool {
-	ok, _ := isTextFileWithReason(path)
-	return ok
+	_, reason, err := detectFile(path)
+	return err == nil && reason == ""
 }
 
-func isTextFileWithReason(path string) (bool, string) {
+func detectFile(path string) (File, string, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return false, ""
+		return File{}, "", err
 	}
 	defer f.Close()
 	buf := make([]byte, maxScanBytes)
-	n, err := f.Read(buf)
-	if err != nil && err != io.EOF {
-		return false, ""
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return File{}, "", err
 	}
-	buf = buf[:n]
-	if n == 0 {
-		return true, ""
+	enc, reason := classify(buf[:n], n == maxScanBytes)
+	if reason != "" {
+		return File{}, reason, nil
 	}
-	for i := 0; i < len(buf); i++ {
-		if buf[i] == 0 {
-			return false, "null byte"
+	var tail []byte
+	if n == maxScanBytes {
+		if tail, err = readTail(f); err != nil {
+			return File{}, "", err
 		}
 	}
-	if !utf8.Valid(buf) {
-		return false, "invalid UTF-8"
+	return File{Path: path, Encoding: enc, Type: DetectType(path, buf[:n], tail)}, "", nil
+}
+
+func readTail(f *os.File) ([]byte, error) {
+	if _, err := f.Seek(-maxTailBytes, io.SeekEnd); err != nil {
+		return nil, err
+	}
+	tail := make([]byte, maxTailBytes)
+	n, err := io.ReadFull(f, tail)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return tail[:n], nil
+}
+
+func classify(buf []byte, partial bool) (string, string) {
+	if len(buf) == 0 {
+		return charset.UTF8, ""
+	}
+	for _, m := range magicNumbers {
+		if bytes.HasPrefix(buf, m.prefix) {
+			return "", m.name
+		}
+	}
+	if enc, ok := charset.DetectUTF16(buf); ok {
+		return enc, ""
+	}
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return "", "null byte"
+	}
+	if partial {
+		buf = trimIncompleteRune(buf)
+	}
+	if utf8.Valid(buf) {
+		return charset.UTF8, ""
+	}
+	if _, ok := charset.InvalidUTF8(buf); ok {
+		return charset.UTF8, ""
+	}
+	if enc, ok := charset.Detect(buf); ok {
+		return enc, ""
+	}
+	return "", "invalid UTF-8"
+}
+
+func trimIncompleteRune(buf []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(buf); i++ {
+		c := buf[len(buf)-i]
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(buf[len(buf)-i:]) {
+				return buf[:len(buf)-i]
+			}
+			break
+		}
+	}
+	return buf
+}
+
+func Ignored(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(path)), "/") {
+		if ignoredDirs[part] {
+			return true
+		}
 	}
-	return true, ""
+	return false
 }
@@ -1,8 +1,10 @@
 package scanner
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"prosefmt/internal/charset"
 	"testing"
 )
 
@@ -97,3 +99,159 @@ func TestScan_RecursiveDir(t *testing.T) {
 		t.Errorf("expected 2 files, got %v", files)
 	}
 }
+
+func TestScanFiles_DetectsEncoding(t *testing.T) {
+	dir := t.TempDir()
+	cp1252 := filepath.Join(dir, "cp1252.txt")
+	utf16 := filepath.Join(dir, "utf16.txt")
+	if err := os.WriteFile(cp1252, []byte("\x93hi\x94\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(utf16, []byte{0xFF, 0xFE, 'h', 0, '\n', 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, skipped, err := ScanFiles([]string{cp1252, utf16}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 || len(files) != 2 {
+		t.Fatalf("expected both files accepted, got %v (skipped %v)", files, skipped)
+	}
+	if files[0].Encoding != charset.Windows1252 || files[1].Encoding != charset.UTF16LE {
+		t.Errorf("expected windows-1252 and utf-16le, got %v", files)
+	}
+}
+
+func TestScan_ExcludesMagicNumbers(t *testing.T) {
+	dir := t.TempDir()
+	pdf := filepath.Join(dir, "doc.pdf")
+	if err := os.WriteFile(pdf, []byte("%PDF-1.4\n% plain header\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, skipped, err := Scan([]string{pdf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 || skipped[pdf] != "PDF document" {
+		t.Errorf("expected PDF rejected by magic number, got files %v skipped %v", files, skipped)
+	}
+}
+
+func TestScan_RuneCutAtSniffBoundary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "long.txt")
+	content := append(bytes.Repeat([]byte("a"), maxScanBytes-1), []byte("\u00e9 tail\n")...)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, skipped, err := ScanFiles([]string{path}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Encoding != charset.UTF8 {
+		t.Errorf("expected UTF-8 file accepted, got %v (skipped %v)", files, skipped)
+	}
+}
+
+func TestScanFiles_MaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	big := filepath.Join(dir, "big.txt")
+	if err := os.WriteFile(big, bytes.Repeat([]byte("x\n"), 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, skipped, err := ScanFiles([]string{big}, Options{MaxFileSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 || skipped[big] == "" {
+		t.Errorf("expected file over max size skipped, got %v (skipped %v)", files, skipped)
+	}
+}
+
+func TestValidate_BinaryTail(t *testing.T) {
+	content := append(bytes.Repeat([]byte("text\n"), 10000), 0x00, 0x01)
+	if _, err := Validate(content); !IsNotText(err) {
+		t.Errorf("expected not-text error, got %v", err)
+	}
+}
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		path string
+		head string
+		want string
+	}{
+		{"main.go", "package main\n", "go"},
+		{"docs/README.md", "# Title\n", "markdown"},
+		{"Makefile", "all:\n\tgo build\n", "makefile"},
+		{"sub/GNUmakefile", "", "makefile"},
+		{"data.tsv", "a\tb\n", "tsv"},
+		{"script", "#!/usr/bin/env python3\nprint()\n", "python"},
+		{"run", "#!/bin/bash -e\n", "shell"},
+		{"notes", "plain\n", "text"},
+		{"build.txt", "# vim: set ft=make:\n", "makefile"},
+		{"init", ";; -*- mode: emacs-lisp; coding: utf-8 -*-\n", "lisp"},
+		{"conf.inc", "# -*- python -*-\n", "python"},
+	}
+	for _, tt := range tests {
+		if got := DetectType(tt.path, []byte(tt.head), nil); got != tt.want {
+			t.Errorf("DetectType(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestScanFiles_SetsType(t *testing.T) {
+	dir := t.TempDir()
+	mk := filepath.Join(dir, "Makefile")
+	if err := os.WriteFile(mk, []byte("all:\n\ttrue\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, _, err := ScanFiles([]string{mk}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Type != "makefile" {
+		t.Errorf("expected makefile type, got %v", files)
+	}
+}
+
+func TestScanFiles_OnErrorContinues(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.txt")
+	if err := os.WriteFile(good, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.txt")
+	var failed []string
+	files, _, err := ScanFiles([]string{missing, good}, Options{OnError: func(path string, err error) {
+		failed = append(failed, path)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != good {
+		t.Errorf("expected good.txt to be scanned, got %v", files)
+	}
+	if len(failed) != 1 || failed[0] != missing {
+		t.Errorf("expected missing.txt reported, got %v", failed)
+	}
+	if _, _, err := ScanFiles([]string{missing}, Options{}); err == nil {
+		t.Error("expected error without OnError")
+	}
+}
+
+func TestScanFiles_TrailingModelineInLargeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "build.txt")
+	content := string(bytes.Repeat([]byte("filler line of text\n"), 4000)) + "# vim: set ft=make:\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, _, err := ScanFiles([]string{path}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Type != "makefile" {
+		t.Errorf("expected makefile type from the trailing modeline, got %v", files)
+	}
+}
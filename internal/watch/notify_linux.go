@@ -0,0 +1,184 @@
+package watch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"prosefmt/internal/scanner"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+type inotify struct {
+	fd     int
+	file   *os.File
+	roots  []watchRoot
+	dirs   map[int32]string
+	events chan string
+	errors chan error
+	done   chan struct{}
+	exited chan struct{}
+}
+
+func newNotifier(paths []string, _ time.Duration) (notifier, error) {
+	rs, err := roots(paths)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	n := &inotify{
+		fd:     fd,
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		roots:  rs,
+		dirs:   make(map[int32]string),
+		events: make(chan string),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	for _, r := range rs {
+		dir := r.path
+		if !r.dir {
+			dir = filepath.Dir(r.path)
+		}
+		if err := n.addTree(dir, r.dir); err != nil {
+			n.file.Close()
+			return nil, err
+		}
+	}
+	go n.loop()
+	return n, nil
+}
+
+func (n *inotify) Events() <-chan string { return n.events }
+func (n *inotify) Errors() <-chan error  { return n.errors }
+
+func (n *inotify) Close() error {
+	close(n.done)
+	err := n.file.Close()
+	<-n.exited
+	return err
+}
+
+func (n *inotify) addTree(dir string, recursive bool) error {
+	if !recursive {
+		return n.add(dir)
+	}
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		if path != dir && scanner.Ignored(fi.Name()) {
+			return filepath.SkipDir
+		}
+		return n.add(path)
+	})
+}
+
+func (n *inotify) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	n.dirs[int32(wd)] = dir
+	return nil
+}
+
+func (n *inotify) loop() {
+	defer close(n.exited)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		k, err := n.file.Read(buf)
+		if err != nil {
+			select {
+			case <-n.done:
+			default:
+				if !errors.Is(err, os.ErrClosed) {
+					n.errors <- err
+				}
+			}
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= k; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+			dir, ok := n.dirs[ev.Wd]
+			if !ok {
+				continue
+			}
+			if ev.Mask&syscall.IN_IGNORED != 0 {
+				delete(n.dirs, ev.Wd)
+				continue
+			}
+			name := string(nameBytes)
+			for len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+			if name == "" {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if ev.Mask&syscall.IN_ISDIR != 0 {
+				if ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && n.recursive(path) && !scanner.Ignored(name) {
+					n.addTree(path, true)
+					if !n.sendTree(path) {
+						return
+					}
+				}
+				continue
+			}
+			if !n.covered(path) {
+				continue
+			}
+			select {
+			case n.events <- path:
+			case <-n.done:
+				return
+			}
+		}
+	}
+}
+
+func (n *inotify) sendTree(dir string) bool {
+	var files []string
+	filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && fi.Mode().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	for _, f := range files {
+		select {
+		case n.events <- f:
+		case <-n.done:
+			return false
+		}
+	}
+	return true
+}
+
+func (n *inotify) covered(path string) bool {
+	for _, r := range n.roots {
+		if r.covers(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *inotify) recursive(path string) bool {
+	for _, r := range n.roots {
+		if r.dir && r.covers(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInotify_CloseStopsLoop(t *testing.T) {
+	n, err := newNotifier([]string{t.TempDir()}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- n.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not stop the event loop")
+	}
+	select {
+	case <-n.(*inotify).exited:
+	default:
+		t.Error("event loop still running after Close")
+	}
+}
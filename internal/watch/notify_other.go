@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watch
+
+import "time"
+
+func newNotifier(paths []string, interval time.Duration) (notifier, error) {
+	return newPoller(paths, interval)
+}
@@ -0,0 +1,101 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"prosefmt/internal/scanner"
+	"time"
+)
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+type poller struct {
+	roots  []watchRoot
+	state  map[string]fileState
+	events chan string
+	errors chan error
+	done   chan struct{}
+}
+
+func newPoller(paths []string, interval time.Duration) (*poller, error) {
+	rs, err := roots(paths)
+	if err != nil {
+		return nil, err
+	}
+	p := &poller{
+		roots:  rs,
+		events: make(chan string),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+	p.state = p.snapshot()
+	go p.loop(interval)
+	return p, nil
+}
+
+func (p *poller) Events() <-chan string { return p.events }
+func (p *poller) Errors() <-chan error  { return p.errors }
+
+func (p *poller) Close() error {
+	close(p.done)
+	return nil
+}
+
+func (p *poller) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		next := p.snapshot()
+		for path, st := range next {
+			if old, ok := p.state[path]; !ok || old != st {
+				if !p.send(path) {
+					return
+				}
+			}
+		}
+		for path := range p.state {
+			if _, ok := next[path]; !ok {
+				if !p.send(path) {
+					return
+				}
+			}
+		}
+		p.state = next
+	}
+}
+
+func (p *poller) send(path string) bool {
+	select {
+	case p.events <- path:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+func (p *poller) snapshot() map[string]fileState {
+	state := make(map[string]fileState)
+	for _, r := range p.roots {
+		filepath.Walk(r.path, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if fi.IsDir() && path != r.path && scanner.Ignored(fi.Name()) {
+				return filepath.SkipDir
+			}
+			if fi.Mode().IsRegular() {
+				state[path] = fileState{size: fi.Size(), modTime: fi.ModTime()}
+			}
+			return nil
+		})
+	}
+	return state
+}
@@ -0,0 +1,101 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"prosefmt/internal/scanner"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultDebounce = 200 * time.Millisecond
+	DefaultInterval = 500 * time.Millisecond
+)
+
+type Options struct {
+	Debounce time.Duration
+	Interval time.Duration
+	Poll     bool
+}
+
+type notifier interface {
+	Events() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+func Watch(ctx context.Context, paths []string, opts Options, onChange func(changed []string)) error {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	var n notifier
+	var err error
+	if opts.Poll {
+		n, err = newPoller(paths, opts.Interval)
+	} else {
+		n, err = newNotifier(paths, opts.Interval)
+	}
+	if err != nil {
+		return err
+	}
+	defer n.Close()
+	pending := make(map[string]bool)
+	timer := time.NewTimer(opts.Debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-n.Errors():
+			return err
+		case p := <-n.Events():
+			if scanner.Ignored(p) {
+				continue
+			}
+			pending[p] = true
+			timer.Reset(opts.Debounce)
+		case <-timer.C:
+			changed := make([]string, 0, len(pending))
+			for p := range pending {
+				changed = append(changed, p)
+			}
+			sort.Strings(changed)
+			pending = make(map[string]bool)
+			onChange(changed)
+		}
+	}
+}
+
+type watchRoot struct {
+	path string
+	dir  bool
+}
+
+func roots(paths []string) ([]watchRoot, error) {
+	var out []watchRoot
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, watchRoot{path: filepath.Clean(p), dir: info.IsDir()})
+	}
+	return out, nil
+}
+
+func (r watchRoot) covers(path string) bool {
+	if !r.dir {
+		return path == r.path
+	}
+	rel, err := filepath.Rel(r.path, path)
+	return err == nil && rel != ".." && !startsWithParent(rel)
+}
+
+func startsWithParent(rel string) bool {
+	return len(rel) >= 3 && rel[:2] == ".." && os.IsPathSeparator(rel[2])
+}
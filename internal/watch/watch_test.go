@@ -0,0 +1,105 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func startWatch(t *testing.T, paths []string, opts Options) <-chan []string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan []string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, paths, opts, func(changed []string) { batches <- changed })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	time.Sleep(50 * time.Millisecond)
+	return batches
+}
+
+func waitBatch(t *testing.T, batches <-chan []string) []string {
+	t.Helper()
+	select {
+	case b := <-batches:
+		return b
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for changes")
+		return nil
+	}
+}
+
+func testWatch(t *testing.T, opts Options) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.MkdirAll(filepath.Join(dir, ".prosefmt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(sub, "a.txt")
+	if err := os.WriteFile(a, []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	batches := startWatch(t, []string{dir}, opts)
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(a, []byte("a  \n"[:2+i]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".prosefmt", "x.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := waitBatch(t, batches)
+	if len(got) != 1 || got[0] != a {
+		t.Errorf("expected one debounced change of %s, got %v", a, got)
+	}
+	select {
+	case b := <-batches:
+		t.Errorf("unexpected extra batch %v", b)
+	case <-time.After(3 * opts.Debounce):
+	}
+}
+
+func TestWatch_Native(t *testing.T) {
+	testWatch(t, Options{Debounce: 300 * time.Millisecond})
+}
+
+func TestWatch_Poll(t *testing.T) {
+	testWatch(t, Options{Debounce: 300 * time.Millisecond, Interval: 50 * time.Millisecond, Poll: true})
+}
+
+func TestWatch_SingleFile(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batches := startWatch(t, []string{a}, Options{Debounce: 100 * time.Millisecond})
+	if err := os.WriteFile(b, []byte("y\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(dir, ".a.txt.swp")
+	if err := os.WriteFile(tmp, []byte("z  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, a); err != nil {
+		t.Fatal(err)
+	}
+	got := waitBatch(t, batches)
+	if len(got) != 1 || got[0] != a {
+		t.Errorf("expected only %s, got %v", a, got)
+	}
+}
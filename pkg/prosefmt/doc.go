@@ -0,0 +1,7 @@
+// Package prosefmt checks and fixes text files with the same rules as the
+// prosefmt command.
+//
+// The exported API follows semantic versioning: within a major version,
+// identifiers are not removed or changed incompatibly and Issue keeps its
+// JSON encoding. Rule IDs and messages may gain new values.
+package prosefmt
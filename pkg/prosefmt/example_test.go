@@ -0,0 +1,79 @@
+package prosefmt_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"prosefmt/pkg/prosefmt"
+)
+
+func ExampleCheckBytes() {
+	issues, err := prosefmt.CheckBytes("notes.txt", []byte("hello  \nworld"), prosefmt.Options{})
+	if err != nil {
+		panic(err)
+	}
+	for _, i := range issues {
+		fmt.Printf("%s:%d:%d: %s: %s\n", i.File, i.Line, i.Column, i.Rule, i.Message)
+	}
+	// Output:
+	// notes.txt:1:6: TL010: no trailing spaces at end of line
+	// notes.txt:1:1: TL001: file must end with exactly one newline
+}
+
+func ExampleFixBytes() {
+	out, err := prosefmt.FixBytes("notes.txt", []byte("hello  \nworld\n\n\n"), prosefmt.Options{Only: []string{"TL010"}})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%q\n", out)
+	// Output:
+	// "hello\nworld\n\n\n"
+}
+
+func ExampleCheckPaths() {
+	dir, _ := os.MkdirTemp("", "prosefmt")
+	defer os.RemoveAll(dir)
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("# Title  \n"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.md"), []byte("ok\n"), 0644)
+
+	issues, err := prosefmt.CheckPaths([]string{dir}, prosefmt.Options{Concurrency: 2})
+	if err != nil {
+		panic(err)
+	}
+	for _, i := range issues {
+		i.File = filepath.Base(i.File)
+		data, _ := json.Marshal(i)
+		fmt.Println(string(data))
+	}
+	// Output:
+	// {"file":"a.md","line":1,"column":8,"rule":"TL010","severity":"error","message":"no trailing spaces at end of line"}
+}
+
+func ExampleRegisterRule() {
+	err := prosefmt.RegisterRule(prosefmt.Rule{
+		ID:          "HOUSE001",
+		Description: "Write the product name as ProseFmt.",
+		Check: func(file string, content []byte) []prosefmt.Issue {
+			if i := bytes.Index(content, []byte("Prosefmt")); i >= 0 {
+				return []prosefmt.Issue{{File: file, Line: 1, Column: i + 1, Message: "write ProseFmt"}}
+			}
+			return nil
+		},
+		Fix: func(content []byte) []byte {
+			return bytes.ReplaceAll(content, []byte("Prosefmt"), []byte("ProseFmt"))
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	issues, _ := prosefmt.CheckBytes("a.txt", []byte("Use Prosefmt\n"), prosefmt.Options{})
+	fmt.Println(issues[0].Rule, issues[0].Message)
+	out, _ := prosefmt.FixBytes("a.txt", []byte("Use Prosefmt\n"), prosefmt.Options{})
+	fmt.Printf("%s", out)
+	// Output:
+	// HOUSE001 write ProseFmt
+	// Use ProseFmt
+}
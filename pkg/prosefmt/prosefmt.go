@@ -0,0 +1,272 @@
+package prosefmt
+
+import (
+	"os"
+	"prosefmt/internal/config"
+	"prosefmt/internal/custom"
+	"prosefmt/internal/plugin"
+	"prosefmt/internal/rules"
+	"prosefmt/internal/scanner"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Issue is a problem found by a rule. Line and Column are 1-based; Column
+// counts bytes. Path-level issues have Line 0.
+type Issue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	EndLine  int    `json:"endLine,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Options select the rules to run. ConfigFile is a .prosefmt.json file;
+// Only and Skip take rule IDs like the --only and --skip flags. Concurrency
+// bounds the files checked in parallel by CheckPaths (default: number of CPUs).
+// The plugin commands of the config run only with AllowPlugins.
+type Options struct {
+	ConfigFile   string
+	Only         []string
+	Skip         []string
+	Concurrency  int
+	AllowPlugins bool
+}
+
+// Rule is a custom content rule for RegisterRule. Fix is optional.
+type Rule struct {
+	ID          string
+	Description string
+	Check       func(file string, content []byte) []Issue
+	Fix         func(content []byte) []byte
+}
+
+// RuleInfo describes a registered rule.
+type RuleInfo struct {
+	ID             string `json:"id"`
+	Description    string `json:"description"`
+	DefaultEnabled bool   `json:"defaultEnabled"`
+	Fixable        bool   `json:"fixable"`
+}
+
+// CheckBytes runs the content rules on content. name is used for file type
+// detection, config overrides and the File of each issue.
+func CheckBytes(name string, content []byte, opts Options) ([]Issue, error) {
+	e, err := newEngine(opts)
+	if err != nil {
+		return nil, err
+	}
+	return fromRules(rules.CheckWith(name, content, e.options(name, detectType(name, content)))), nil
+}
+
+// FixBytes returns content with every fixable issue fixed. Like the write
+// command, it fails when the fixes do not converge or a rule's issues remain.
+func FixBytes(name string, content []byte, opts Options) ([]byte, error) {
+	e, err := newEngine(opts)
+	if err != nil {
+		return nil, err
+	}
+	out, err := rules.FixChecked(name, content, e.options(name, detectType(name, content)))
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CheckPaths scans files and directories like the check command, skipping
+// binary files, and returns the content and path issues sorted by position.
+func CheckPaths(paths []string, opts Options) ([]Issue, error) {
+	e, err := newEngine(opts)
+	if err != nil {
+		return nil, err
+	}
+	scanned, _, err := scanner.ScanFiles(paths, scanner.Options{})
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(scanned))
+	roots := make(map[string]string, len(scanned))
+	files := make([]string, 0, len(scanned))
+	for _, f := range scanned {
+		types[f.Path] = f.Type
+		roots[f.Path] = f.Root
+		files = append(files, f.Path)
+	}
+	optionsFor := func(path string) rules.Options {
+		o := e.options(path, types[path])
+		o.Root = roots[path]
+		return o
+	}
+	issues := rules.CheckPaths(files, optionsFor)
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				found, err := checkFile(path, optionsFor(path))
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				issues = append(issues, found...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range files {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	out := fromRules(issues)
+	sort.Slice(out, func(a, b int) bool {
+		if out[a].File != out[b].File {
+			return out[a].File < out[b].File
+		}
+		if out[a].Line != out[b].Line {
+			return out[a].Line < out[b].Line
+		}
+		if out[a].Column != out[b].Column {
+			return out[a].Column < out[b].Column
+		}
+		return out[a].Rule < out[b].Rule
+	})
+	return out, nil
+}
+
+// RegisterRule adds a rule that runs, enabled by default, after the built-in
+// rules and before the final-newline rule. Checks already running when it is
+// called do not see the new rule; register rules during initialization.
+func RegisterRule(r Rule) error {
+	check := r.Check
+	ir := rules.Rule{
+		ID:             r.ID,
+		Description:    r.Description,
+		DefaultEnabled: true,
+	}
+	if check != nil {
+		ir.Check = func(file string, content []byte, _ rules.Options) []rules.Issue {
+			found := check(file, content)
+			out := make([]rules.Issue, 0, len(found))
+			for _, i := range found {
+				if i.Rule == "" {
+					i.Rule = r.ID
+				}
+				out = append(out, rules.Issue{File: i.File, Line: i.Line, Column: i.Column, EndLine: i.EndLine, RuleID: i.Rule, Message: i.Message})
+			}
+			return out
+		}
+	}
+	if fix := r.Fix; fix != nil {
+		ir.Fix = func(content []byte, _ rules.Options) []byte {
+			return fix(content)
+		}
+	}
+	return rules.Register(ir)
+}
+
+// Rules lists the built-in, path and registered rules sorted by ID.
+func Rules() []RuleInfo {
+	var out []RuleInfo
+	for _, r := range rules.All() {
+		out = append(out, RuleInfo{ID: r.ID, Description: r.Description, DefaultEnabled: r.DefaultEnabled, Fixable: r.Fix != nil})
+	}
+	for _, r := range rules.PathRules() {
+		out = append(out, RuleInfo{ID: r.ID, Description: r.Description, DefaultEnabled: r.DefaultEnabled})
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ID < out[b].ID })
+	return out
+}
+
+type engine struct {
+	cfg   *config.Config
+	sel   rules.Selection
+	extra []rules.Rule
+}
+
+func newEngine(opts Options) (*engine, error) {
+	e := &engine{}
+	var plugins, customRules []rules.Rule
+	if opts.ConfigFile != "" {
+		cfg, err := config.Load(opts.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		plugins = plugin.Rules(cfg)
+		if customRules, err = custom.Rules(cfg); err != nil {
+			return nil, err
+		}
+		if err := rules.ValidateExtra(append(plugins, customRules...)); err != nil {
+			return nil, err
+		}
+		e.extra = customRules
+		if opts.AllowPlugins {
+			e.extra = append(plugins, customRules...)
+		}
+		e.cfg = cfg
+	}
+	only, skip := opts.Only, append([]string(nil), opts.Skip...)
+	if e.cfg != nil {
+		if len(only) == 0 {
+			only = e.cfg.Only
+		}
+		skip = append(skip, e.cfg.Skip...)
+	}
+	sel, err := rules.NewSelection(only, skip, append(plugins, customRules...)...)
+	if err != nil {
+		return nil, err
+	}
+	e.sel = sel
+	return e, nil
+}
+
+func (e *engine) options(file, fileType string) rules.Options {
+	return rules.Options{File: file, Type: fileType, Rules: e.cfg.For(file, fileType), Select: e.sel, Extra: e.extra}
+}
+
+func detectType(name string, content []byte) string {
+	head, tail := scanner.HeadTail(content)
+	return scanner.DetectType(name, head, tail)
+}
+
+func checkFile(path string, opts rules.Options) ([]rules.Issue, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := scanner.Validate(content); err != nil {
+		return nil, nil
+	}
+	return rules.CheckWith(path, content, opts), nil
+}
+
+func fromRules(issues []rules.Issue) []Issue {
+	out := make([]Issue, 0, len(issues))
+	for _, i := range issues {
+		out = append(out, Issue{
+			File:     i.File,
+			Line:     i.Line,
+			Column:   i.Column,
+			EndLine:  i.EndLine,
+			Rule:     i.RuleID,
+			Severity: i.Severity.String(),
+			Message:  i.Message,
+		})
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package prosefmt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckBytes_ConfigFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".prosefmt.json")
+	if err := os.WriteFile(file, []byte(`{"overrides": [{"files": ["*.md"], "rules": {"TL010": {"severity": "warning"}}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := CheckBytes(filepath.Join(filepath.Dir(file), "a.md"), []byte("x \n"), Options{ConfigFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Severity != "warning" {
+		t.Errorf("expected one warning, got %v", issues)
+	}
+}
+
+func TestOptions_Errors(t *testing.T) {
+	if _, err := CheckBytes("a", nil, Options{Only: []string{"TL999"}}); err == nil {
+		t.Error("expected unknown rule error")
+	}
+	if _, err := FixBytes("a", nil, Options{ConfigFile: filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("expected missing config error")
+	}
+	if err := RegisterRule(Rule{ID: "TL001", Check: func(string, []byte) []Issue { return nil }}); err == nil {
+		t.Error("expected duplicate rule error")
+	}
+}
+
+func TestRules(t *testing.T) {
+	var tl001, tl040 RuleInfo
+	for _, r := range Rules() {
+		switch r.ID {
+		case "TL001":
+			tl001 = r
+		case "TL040":
+			tl040 = r
+		}
+	}
+	if !tl001.Fixable || !tl001.DefaultEnabled || tl040.Fixable || tl040.Description == "" {
+		t.Errorf("unexpected rule info %+v %+v", tl001, tl040)
+	}
+}
+
+func TestCheckBytes_CustomRulesPerConfig(t *testing.T) {
+	var files []string
+	for _, pattern := range []string{"foo", "bar"} {
+		file := filepath.Join(t.TempDir(), ".prosefmt.json")
+		cfg := `{"customRules": [{"id": "STYLE001", "message": "no ` + pattern + `", "pattern": "` + pattern + `"}]}`
+		if err := os.WriteFile(file, []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	for k, want := range []string{"no foo", "no bar"} {
+		issues, err := CheckBytes("a.txt", []byte("foo bar\n"), Options{ConfigFile: files[k]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != 1 || issues[0].Message != want {
+			t.Errorf("config %d: expected %q, got %v", k, want, issues)
+		}
+	}
+}
+
+func TestFixBytes_DoesNotConverge(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".prosefmt.json")
+	if err := os.WriteFile(file, []byte(`{"customRules": [{"id": "STYLE001", "message": "no x", "pattern": "x+", "replacement": "xx$0"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := FixBytes("a.txt", []byte("x\n"), Options{ConfigFile: file})
+	if err == nil || !strings.Contains(err.Error(), "STYLE001: fixes do not converge") || out != nil {
+		t.Errorf("expected an error naming STYLE001, got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,117 @@
+package main_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func exitCode(t *testing.T, dir string, exe string, args ...string) int {
+	t.Helper()
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		t.Fatalf("run %v: %v\n%s", args, err, out)
+	}
+	return cmd.ProcessState.ExitCode()
+}
+
+func TestIntegration_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.txt"), []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.txt"), []byte("x  \ny  \n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "x.bin"), []byte("\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"clean", []string{"check", "good.txt"}, 0},
+		{"issues", []string{"check", "bad.txt"}, 1},
+		{"default command issues", []string{"bad.txt"}, 1},
+		{"unknown flag", []string{"check", "--nope", "good.txt"}, 2},
+		{"missing path", []string{"check", "missing.txt"}, 2},
+		{"unknown rule", []string{"check", "--only", "TL999", "good.txt"}, 2},
+		{"bad config", []string{"check", "--config", "missing.json", "good.txt"}, 2},
+		{"nothing to check", []string{"check", "x.bin"}, 0},
+		{"nothing to check configured", []string{"check", "--no-files-exit-code", "3", "x.bin"}, 3},
+		{"write nothing to check configured", []string{"write", "--no-files-exit-code", "3", "x.bin"}, 3},
+		{"bad no-files exit code", []string{"check", "--no-files-exit-code", "300", "x.bin"}, 2},
+		{"within budget", []string{"check", "--max-issues", "3", "bad.txt"}, 0},
+		{"at budget", []string{"check", "--max-issues", "3", "bad.txt", "good.txt"}, 0},
+		{"over budget", []string{"check", "--max-issues", "2", "bad.txt"}, 1},
+		{"zero budget", []string{"check", "--max-issues", "0", "bad.txt"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(t, dir, exe, tt.args...); got != tt.want {
+				t.Errorf("prosefmt %v: exit %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntegration_KeepGoing(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(bad, []byte("x  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "check", "missing.txt", "bad.txt")
+	cmd.Dir = dir
+	out, _ := cmd.CombinedOutput()
+	if cmd.ProcessState.ExitCode() != 2 {
+		t.Errorf("expected exit 2 with a per-file error, got %d\n%s", cmd.ProcessState.ExitCode(), out)
+	}
+	if !strings.Contains(string(out), "bad.txt:1:2: error: TL010:") {
+		t.Errorf("expected remaining files to be checked, got %s", out)
+	}
+	if !strings.Contains(string(out), "1 file(s) could not be processed:\nmissing.txt: stat: no such file or directory\n") {
+		t.Errorf("expected error section, got %s", out)
+	}
+
+	if got := exitCode(t, dir, exe, "write", "missing.txt", "bad.txt"); got != 2 {
+		t.Errorf("write: exit %d, want 2", got)
+	}
+	if after, _ := os.ReadFile(bad); string(after) != "x  \n" {
+		t.Errorf("write without --keep-going should stop before fixing, got %q", after)
+	}
+	if got := exitCode(t, dir, exe, "write", "--keep-going", "missing.txt", "bad.txt"); got != 2 {
+		t.Errorf("write --keep-going: exit %d, want 2", got)
+	}
+	if after, _ := os.ReadFile(bad); string(after) != "x\n" {
+		t.Errorf("write --keep-going should fix remaining files, got %q", after)
+	}
+}
+
+func TestIntegration_KeepGoing_Unreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	dir := t.TempDir()
+	locked := filepath.Join(dir, "locked.txt")
+	if err := os.WriteFile(locked, []byte("x\n"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ok.txt"), []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "check", ".")
+	cmd.Dir = dir
+	out, _ := cmd.CombinedOutput()
+	if cmd.ProcessState.ExitCode() != 2 || !strings.Contains(string(out), "locked.txt: open: permission denied") {
+		t.Errorf("expected permission error reported with exit 2, got %d\n%s", cmd.ProcessState.ExitCode(), out)
+	}
+}
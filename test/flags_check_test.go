@@ -64,3 +64,124 @@ func TestIntegration_Default_IsCheck(t *testing.T) {
 		t.Errorf("expected default to run check and report TL010, got %s", out)
 	}
 }
+
+func TestIntegration_Check_SkipRule(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(bad, []byte("x\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "check", "--skip", "TL001,TL010", bad)
+	out, _ := cmd.CombinedOutput()
+	if cmd.ProcessState.ExitCode() != 0 {
+		t.Errorf("expected exit 0 with TL001 skipped, got %d\n%s", cmd.ProcessState.ExitCode(), out)
+	}
+}
+
+func TestIntegration_Check_UnknownRuleID(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.txt")
+	if err := os.WriteFile(good, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "check", "--only", "TL999", good)
+	out, _ := cmd.CombinedOutput()
+	if cmd.ProcessState.ExitCode() == 0 {
+		t.Errorf("expected non-zero exit for unknown rule ID\n%s", out)
+	}
+	if !strings.Contains(string(out), "unknown rule ID") || !strings.Contains(string(out), "TL010") {
+		t.Errorf("expected error listing valid IDs, got %s", out)
+	}
+}
+
+func TestIntegration_Check_FailOn(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".prosefmt.json"), []byte(`{"overrides": [{"files": ["*.md"], "rules": {"TL010": {"severity": "warning"}}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("x  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "check", "notes.md")
+	cmd.Dir = dir
+	out, _ := cmd.CombinedOutput()
+	if cmd.ProcessState.ExitCode() != 0 {
+		t.Errorf("expected exit 0 for a warning with default --fail-on, got %d\n%s", cmd.ProcessState.ExitCode(), out)
+	}
+	if !strings.Contains(string(out), "notes.md:1:2: warning: TL010:") {
+		t.Errorf("expected warning severity in report, got %s", out)
+	}
+	cmd = exec.Command(exe, "check", "--fail-on", "warning", "notes.md")
+	cmd.Dir = dir
+	out, _ = cmd.CombinedOutput()
+	if cmd.ProcessState.ExitCode() != 1 {
+		t.Errorf("expected exit 1 with --fail-on warning, got %d\n%s", cmd.ProcessState.ExitCode(), out)
+	}
+}
+
+func TestIntegration_Check_Plugin(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncat >/dev/null\necho '{\"issues\": [{\"line\": 1, \"column\": 1, \"message\": \"missing license header\"}]}'\n"
+	if err := os.WriteFile(filepath.Join(dir, "license.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"plugins": [{"id": "HOUSE010", "command": ["./license.sh"], "files": ["*.md"]}], "rules": {"HOUSE010": {"severity": "warning"}}}`
+	if err := os.WriteFile(filepath.Join(dir, ".prosefmt.json"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("# Title\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "check", "a.md")
+	cmd.Dir = dir
+	out, _ := cmd.CombinedOutput()
+	if strings.Contains(string(out), "HOUSE010") || !strings.Contains(string(out), "pass --allow-plugins") {
+		t.Errorf("expected plugins not to run without --allow-plugins, got %s", out)
+	}
+	cmd = exec.Command(exe, "check", "--allow-plugins", "a.md")
+	cmd.Dir = dir
+	out, _ = cmd.CombinedOutput()
+	if !strings.Contains(string(out), "a.md:1:1: warning: HOUSE010: missing license header") {
+		t.Errorf("expected plugin issue in report, got %s", out)
+	}
+	if cmd.ProcessState.ExitCode() != 0 {
+		t.Errorf("expected exit 0 for a plugin warning, got %d", cmd.ProcessState.ExitCode())
+	}
+}
+
+func TestIntegration_Check_Pretty(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("ok\nhello  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "check", "--pretty", "a.txt", "missing.txt")
+	cmd.Dir = dir
+	out, _ := cmd.CombinedOutput()
+	for _, want := range []string{
+		"a.txt\n  2:6      error    TL010  no trailing spaces at end of line\n    2 | hello\u00b7\u00b7\n      |      ^^\n",
+		"Rule   Issues  Files\nTL010       1      1\n",
+		"1 file(s) could not be processed:\nmissing.txt: stat: no such file or directory\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in output, got %s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "\x1b[") {
+		t.Errorf("expected no colors when stdout is not a terminal, got %q", out)
+	}
+	cmd = exec.Command(exe, "check", "a.txt")
+	cmd.Dir = dir
+	if out, _ := cmd.CombinedOutput(); !strings.Contains(string(out), "a.txt:2:6: error: TL010: ") {
+		t.Errorf("expected compact output when stdout is not a terminal, got %s", out)
+	}
+	cmd = exec.Command(exe, "check", "--pretty", "--compact", "a.txt")
+	cmd.Dir = dir
+	if out, _ := cmd.CombinedOutput(); cmd.ProcessState.ExitCode() != 2 {
+		t.Errorf("expected exit 2 for --pretty with --compact, got %d: %s", cmd.ProcessState.ExitCode(), out)
+	}
+}
@@ -51,3 +51,259 @@ func TestIntegration_Write_ExitCodeZero(t *testing.T) {
 		t.Errorf("expected exit 0 for write, got %d", cmd.ProcessState.ExitCode())
 	}
 }
+
+func TestIntegration_Write_SkipsConflictMarkers(t *testing.T) {
+	dir := t.TempDir()
+	conflicted := filepath.Join(dir, "conflicted.txt")
+	content := []byte("<<<<<<< HEAD\nours  \n=======\ntheirs\n>>>>>>> branch\n")
+	if err := os.WriteFile(conflicted, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "write", conflicted)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("write: %v\n%s", err, out)
+	}
+	after, err := os.ReadFile(conflicted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(content) {
+		t.Errorf("expected conflicted file untouched, got %q", after)
+	}
+	if !strings.Contains(string(out), "merge conflict markers") {
+		t.Errorf("expected output to mention merge conflict markers, got %s", out)
+	}
+}
+
+func TestIntegration_Write_ConvertEncoding(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.txt")
+	if err := os.WriteFile(legacy, []byte("\x93quoted\x94\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	check := exec.Command(exe, "check", legacy)
+	out, _ := check.CombinedOutput()
+	if check.ProcessState.ExitCode() != 1 || !strings.Contains(string(out), "detected windows-1252") {
+		t.Fatalf("expected check to report windows-1252, got %d\n%s", check.ProcessState.ExitCode(), out)
+	}
+	write := exec.Command(exe, "write", legacy)
+	if out, err := write.CombinedOutput(); err != nil {
+		t.Fatalf("write: %v\n%s", err, out)
+	}
+	if after, _ := os.ReadFile(legacy); string(after) != "\x93quoted\x94\r\n" {
+		t.Errorf("expected file untouched without --convert-encoding, got %q", after)
+	}
+	convert := exec.Command(exe, "write", "--convert-encoding", legacy)
+	if out, err := convert.CombinedOutput(); err != nil {
+		t.Fatalf("write --convert-encoding: %v\n%s", err, out)
+	}
+	if after, _ := os.ReadFile(legacy); string(after) != "\u201cquoted\u201d\r\n" {
+		t.Errorf("expected UTF-8 with CRLF preserved, got %q", after)
+	}
+}
+
+func TestIntegration_Write_ConvertEncodingRefusesDamagedUTF8(t *testing.T) {
+	dir := t.TempDir()
+	damaged := filepath.Join(dir, "damaged.txt")
+	content := []byte("caf\u00e9 na\u00efve \u2014 r\xe9sum\u00e9\n")
+	if err := os.WriteFile(damaged, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	check := exec.Command(exe, "check", "--compact", damaged)
+	out, _ := check.CombinedOutput()
+	if check.ProcessState.ExitCode() != 1 || !strings.Contains(string(out), "damaged.txt:1:19: error: TL002: invalid UTF-8") {
+		t.Fatalf("expected check to report invalid UTF-8 at 1:19, got %d\n%s", check.ProcessState.ExitCode(), out)
+	}
+	convert := exec.Command(exe, "write", "--convert-encoding", damaged)
+	if out, err := convert.CombinedOutput(); err != nil {
+		t.Fatalf("write --convert-encoding: %v\n%s", err, out)
+	}
+	if after, _ := os.ReadFile(damaged); string(after) != string(content) {
+		t.Errorf("expected damaged UTF-8 left untouched, got %q", after)
+	}
+}
+
+func TestIntegration_Write_OnlyRule(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(bad, []byte("x  \n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "write", "--only", "TL010", bad)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("write --only: %v\n%s", err, out)
+	}
+	after, err := os.ReadFile(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != "x\n\n" {
+		t.Errorf("expected only trailing spaces removed, got %q", after)
+	}
+}
+
+func TestIntegration_Write_AtomicBatch(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "a  \n", "b.txt": "b\n\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "write", "--atomic-batch", dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("write --atomic-batch: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "Wrote 2 file(s):") {
+		t.Errorf("expected 2 files written, got %s", out)
+	}
+	for name, want := range map[string]string{"a.txt": "a\n", "b.txt": "b\n"} {
+		got, _ := os.ReadFile(filepath.Join(dir, name))
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected no staged or backup files left, got %d entries", len(entries))
+	}
+}
+
+func TestIntegration_Write_BackupAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(a, []byte("a  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	if got := exitCode(t, dir, exe, "write", "--backup", "."); got != 0 {
+		t.Fatalf("write --backup: exit %d", got)
+	}
+	if got := exitCode(t, dir, exe, "check", "."); got != 0 {
+		t.Errorf("check after write: exit %d, backups must not be scanned", got)
+	}
+	cmd := exec.Command(exe, "undo")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("undo: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "Restored 1 file(s):\na.txt\n") {
+		t.Errorf("expected restored list, got %s", out)
+	}
+	if after, _ := os.ReadFile(a); string(after) != "a  \n" {
+		t.Errorf("expected original content, got %q", after)
+	}
+
+	if got := exitCode(t, dir, exe, "write", "--backup", "."); got != 0 {
+		t.Fatalf("write --backup: exit %d", got)
+	}
+	if err := os.WriteFile(a, []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := exitCode(t, dir, exe, "undo"); got != 2 {
+		t.Errorf("undo of a modified file: exit %d, want 2", got)
+	}
+	if after, _ := os.ReadFile(a); string(after) != "edited\n" {
+		t.Errorf("expected modified file kept, got %q", after)
+	}
+}
+
+func TestIntegration_Write_BackupKeptWhenALaterFileFails(t *testing.T) {
+	dir := t.TempDir()
+	cfg := `{"customRules": [{"id": "STYLE001", "message": "no x", "pattern": "x+", "replacement": "xx$0", "files": ["3.txt"]}]}`
+	if err := os.WriteFile(filepath.Join(dir, ".prosefmt.json"), []byte(cfg+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"1.txt", "2.txt", "3.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x  \n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exe := buildBinary(t)
+	if got := exitCode(t, dir, exe, "write", "--backup", "."); got != 2 {
+		t.Fatalf("write --backup: exit %d, want 2", got)
+	}
+	for _, name := range []string{"1.txt", "2.txt"} {
+		if after, _ := os.ReadFile(filepath.Join(dir, name)); string(after) != "x\n" {
+			t.Fatalf("%s: expected it to be fixed before 3.txt failed, got %q", name, after)
+		}
+	}
+	cmd := exec.Command(exe, "undo")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("undo: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "Restored 2 file(s):\n1.txt\n2.txt\n") {
+		t.Errorf("expected the first two files restored, got %s", out)
+	}
+	for _, name := range []string{"1.txt", "2.txt", "3.txt"} {
+		if after, _ := os.ReadFile(filepath.Join(dir, name)); string(after) != "x  \n" {
+			t.Errorf("%s: expected original content, got %q", name, after)
+		}
+	}
+}
+
+func TestIntegration_Write_CustomRule(t *testing.T) {
+	dir := t.TempDir()
+	cfg := `{"customRules": [{"id": "STYLE001", "message": "write e-mail", "pattern": "\\b[Ee]mail\\b", "replacement": "e-mail"}]}`
+	if err := os.WriteFile(filepath.Join(dir, ".prosefmt.json"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "a.md")
+	if err := os.WriteFile(file, []byte("Send an\nemail  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "check", "a.md")
+	cmd.Dir = dir
+	out, _ := cmd.CombinedOutput()
+	if !strings.Contains(string(out), "a.md:2:1: error: STYLE001: write e-mail") {
+		t.Errorf("expected custom rule issue in report, got %s", out)
+	}
+	cmd = exec.Command(exe, "write", "a.md")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("write: %v\n%s", err, out)
+	}
+	got, _ := os.ReadFile(file)
+	if string(got) != "Send an\ne-mail\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestIntegration_Write_Interactive(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.md")
+	if err := os.WriteFile(file, []byte("keep  \ntrim  \n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe := buildBinary(t)
+	cmd := exec.Command(exe, "write", "--interactive", "a.md")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader("n\ny\ny\n")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("write --interactive: %v\n%s", err, out)
+	}
+	if strings.Count(string(out), "Apply this fix") != 3 || strings.Contains(string(out), "\x1b[") {
+		t.Errorf("expected three uncolored prompts, got %s", out)
+	}
+	got, _ := os.ReadFile(file)
+	if string(got) != "keep  \ntrim\n" {
+		t.Errorf("got %q", got)
+	}
+	cmd = exec.Command(exe, "write", "--interactive", "--atomic-batch", "a.md")
+	cmd.Dir = dir
+	if out, _ := cmd.CombinedOutput(); cmd.ProcessState.ExitCode() != 2 || !strings.Contains(string(out), "cannot be combined") {
+		t.Errorf("expected exit 2 for --interactive with --atomic-batch, got %d: %s", cmd.ProcessState.ExitCode(), out)
+	}
+}